@@ -0,0 +1,252 @@
+package zen
+
+// This file contains the access log formats of the Logger middleware: the
+// Apache Common and Combined formats, JSON lines, and templates such as
+// "${status} ${latency} ${method} ${path}" built from the tags below.
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Access log formats accepted by LoggerConfig.Format, besides LogFormatJSON and templates
+const (
+	LogFormatCommon   = "common"   // Apache Common Log Format
+	LogFormatCombined = "combined" // Apache Combined Log Format
+)
+
+// accessLogFunc formats the access log line of a request, without the newline
+type accessLogFunc func(c *Context, start time.Time, latency time.Duration) string
+
+// newAccessLogFunc returns the formatter of cfg, or nil for the default format.
+// It panics on an unknown format or template tag, which are programming errors.
+func newAccessLogFunc(cfg LoggerConfig) accessLogFunc {
+	if cfg.Formatter != nil {
+		return func(c *Context, _ time.Time, latency time.Duration) string {
+			return strings.TrimSuffix(cfg.Formatter(c, latency), "\n")
+		}
+	}
+
+	switch strings.ToLower(cfg.Format) {
+	case "", LogFormatText:
+		return nil
+	case LogFormatCommon:
+		return commonLog
+	case LogFormatCombined:
+		return combinedLog
+	case LogFormatJSON:
+		return jsonLog
+	}
+	if strings.Contains(cfg.Format, "${") {
+		format, err := parseLogTemplate(cfg.Format)
+		if err != nil {
+			panic(err)
+		}
+		return format
+	}
+	panic(fmt.Sprintf("zen: unknown access log format %q", cfg.Format))
+}
+
+// commonLog formats a line of the Apache Common Log Format, e.g.
+// 127.0.0.1 - alice [02/Jan/2024:15:04:05 +0000] "GET /users?page=2 HTTP/1.1" 200 512
+func commonLog(c *Context, start time.Time, _ time.Duration) string {
+	return fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`,
+		c.GetClientIP(),
+		dashIfEmpty(logUser(c)),
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		c.Request.Method,
+		escapeLogField(c.Request.URL.RequestURI()),
+		c.Request.Proto,
+		c.Writer.Status(),
+		responseBytes(c),
+	)
+}
+
+// combinedLog formats a line of the Apache Combined Log Format, the Common
+// format followed by the referer and user agent
+func combinedLog(c *Context, start time.Time, latency time.Duration) string {
+	return fmt.Sprintf(`%s "%s" "%s"`,
+		commonLog(c, start, latency),
+		escapeLogField(dashIfEmpty(c.Request.Referer())),
+		escapeLogField(dashIfEmpty(c.Request.UserAgent())),
+	)
+}
+
+// accessLogEntry is a line of the JSON access log
+type accessLogEntry struct {
+	Time      string  `json:"time"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Query     string  `json:"query,omitempty"`
+	Route     string  `json:"route,omitempty"`
+	Status    int     `json:"status"`
+	Latency   string  `json:"latency"`
+	LatencyMS float64 `json:"latency_ms"`
+	Bytes     int64   `json:"bytes"`
+	ClientIP  string  `json:"client_ip"`
+	UserAgent string  `json:"user_agent,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+	UserID    string  `json:"user_id,omitempty"`
+}
+
+// jsonLog formats a JSON line, e.g.
+// {"time":"2024-01-02T15:04:05Z","method":"GET","path":"/users/42","route":"/users/:id","status":200,...}
+func jsonLog(c *Context, start time.Time, latency time.Duration) string {
+	line, _ := json.Marshal(accessLogEntry{
+		Time:      start.Format(time.RFC3339),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Query:     c.Request.URL.RawQuery,
+		Route:     c.RoutePattern(),
+		Status:    c.Writer.Status(),
+		Latency:   latency.String(),
+		LatencyMS: float64(latency) / float64(time.Millisecond),
+		Bytes:     c.Writer.Size(),
+		ClientIP:  c.GetClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: c.RequestID(),
+		UserID:    c.UserID(),
+	})
+	return string(line)
+}
+
+// logTags are the tags of access log templates
+var logTags = map[string]accessLogFunc{
+	"time": func(_ *Context, start time.Time, _ time.Duration) string {
+		return start.Format(time.RFC3339)
+	},
+	"status": func(c *Context, _ time.Time, _ time.Duration) string {
+		return strconv.Itoa(c.Writer.Status())
+	},
+	"latency": func(_ *Context, _ time.Time, latency time.Duration) string {
+		return latency.String()
+	},
+	"latency_ms": func(_ *Context, _ time.Time, latency time.Duration) string {
+		return strconv.FormatFloat(float64(latency)/float64(time.Millisecond), 'f', 3, 64)
+	},
+	"method": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.Request.Method
+	},
+	"path": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.Request.URL.Path
+	},
+	"query": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.Request.URL.RawQuery
+	},
+	"uri": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.Request.URL.RequestURI()
+	},
+	"route": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.RoutePattern()
+	},
+	"protocol": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.Request.Proto
+	},
+	"host": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.Request.Host
+	},
+	"ip": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.GetClientIP()
+	},
+	"bytes": func(c *Context, _ time.Time, _ time.Duration) string {
+		return strconv.FormatInt(c.Writer.Size(), 10)
+	},
+	"user_agent": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.Request.UserAgent()
+	},
+	"referer": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.Request.Referer()
+	},
+	"request_id": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.RequestID()
+	},
+	"user_id": func(c *Context, _ time.Time, _ time.Duration) string {
+		return c.UserID()
+	},
+}
+
+// parseLogTemplate compiles a template such as "${status} ${latency} ${path}".
+// Besides the tags of logTags, "${header:Name}" is replaced by a request header.
+func parseLogTemplate(template string) (accessLogFunc, error) {
+	var parts []accessLogFunc
+	for template != "" {
+		start := strings.Index(template, "${")
+		if start < 0 {
+			parts = append(parts, literalLogPart(template))
+			break
+		}
+		if start > 0 {
+			parts = append(parts, literalLogPart(template[:start]))
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("zen: unclosed tag in access log template %q", template)
+		}
+		tag := template[start+2 : start+end]
+		template = template[start+end+1:]
+
+		part, ok := logTags[tag]
+		if header, isHeader := strings.CutPrefix(tag, "header:"); isHeader {
+			part, ok = func(c *Context, _ time.Time, _ time.Duration) string {
+				return c.GetHeader(header)
+			}, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("zen: unknown access log tag ${%s}", tag)
+		}
+		parts = append(parts, func(c *Context, start time.Time, latency time.Duration) string {
+			return escapeLogField(part(c, start, latency))
+		})
+	}
+
+	return func(c *Context, start time.Time, latency time.Duration) string {
+		var b strings.Builder
+		for _, part := range parts {
+			b.WriteString(part(c, start, latency))
+		}
+		return b.String()
+	}, nil
+}
+
+// literalLogPart returns a template part writing text as it is
+func literalLogPart(text string) accessLogFunc {
+	return func(*Context, time.Time, time.Duration) string { return text }
+}
+
+// logUser returns the authenticated user, see Context.UserID, or the user name
+// of the request's basic auth credentials
+func logUser(c *Context) string {
+	if id := c.UserID(); id != "" {
+		return escapeLogField(id)
+	}
+	user, _, _ := c.Request.BasicAuth()
+	return escapeLogField(user)
+}
+
+// responseBytes returns the size of the response body, "-" for none like Apache
+func responseBytes(c *Context) string {
+	if size := c.Writer.Size(); size > 0 {
+		return strconv.FormatInt(size, 10)
+	}
+	return "-"
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// escapeLogField escapes quotes, backslashes and control characters, so a
+// client can't break up or forge log lines
+func escapeLogField(s string) string {
+	if strings.IndexFunc(s, func(r rune) bool { return r == '"' || r == '\\' || r < ' ' || r == 0x7f }) < 0 {
+		return s
+	}
+	quoted := strconv.Quote(s)
+	return quoted[1 : len(quoted)-1]
+}
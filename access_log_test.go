@@ -0,0 +1,114 @@
+package zen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// accessLog serves a request to /users/42 through the Logger middleware and
+// returns what it logged
+func accessLog(t *testing.T, cfg LoggerConfig, header http.Header) string {
+	t.Helper()
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	engine := New()
+	engine.SetMode(Test)
+	engine.Apply(Logger(cfg))
+	engine.GET("/users/:id", func(c *Context) {
+		c.Text(http.StatusCreated, "hello")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42?page=2", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	return out.String()
+}
+
+func TestLogger_CommonAndCombined(t *testing.T) {
+	header := http.Header{"User-Agent": {`curl/8.0 "quoted"`}, "Referer": {"https://example.com/"}}
+
+	common := accessLog(t, LoggerConfig{Format: LogFormatCommon}, header)
+	pattern := `^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /users/42\?page=2 HTTP/1\.1" 201 5\n$`
+	if !regexp.MustCompile(pattern).MatchString(common) {
+		t.Errorf("Unexpected common log line %q", common)
+	}
+
+	combined := accessLog(t, LoggerConfig{Format: LogFormatCombined}, header)
+	if !strings.HasSuffix(combined, `201 5 "https://example.com/" "curl/8.0 \"quoted\""`+"\n") {
+		t.Errorf("Unexpected combined log line %q", combined)
+	}
+}
+
+func TestLogger_JSONFormat(t *testing.T) {
+	out := accessLog(t, LoggerConfig{Format: LogFormatJSON}, http.Header{"X-Request-Id": {"req-1"}})
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &entry); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", out, err)
+	}
+	want := map[string]interface{}{
+		"method":     "GET",
+		"path":       "/users/42",
+		"query":      "page=2",
+		"route":      "/users/:id",
+		"status":     float64(201),
+		"bytes":      float64(5),
+		"client_ip":  "10.0.0.1",
+		"request_id": "req-1",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, entry[key])
+		}
+	}
+	if _, ok := entry["latency_ms"].(float64); !ok {
+		t.Errorf("Expected a numeric latency, got %v", entry["latency_ms"])
+	}
+}
+
+func TestLogger_Template(t *testing.T) {
+	out := accessLog(t, LoggerConfig{Format: "${status} ${method} ${route} ${uri} ${bytes}B tenant=${header:X-Tenant}"},
+		http.Header{"X-Tenant": {"acme\nFAKE LINE"}})
+
+	if out != `201 GET /users/:id /users/42?page=2 5B tenant=acme\nFAKE LINE`+"\n" {
+		t.Errorf("Unexpected template line %q", out)
+	}
+
+	for _, format := range []string{"${status} ${unknown}", "${status", "apache"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected a panic for the format %q", format)
+				}
+			}()
+			Logger(LoggerConfig{Format: format})
+		}()
+	}
+}
+
+func TestLogger_Formatter(t *testing.T) {
+	out := accessLog(t, LoggerConfig{
+		Format: LogFormatJSON,
+		Formatter: func(c *Context, latency time.Duration) string {
+			return fmt.Sprintf("[API] %s %d", c.RoutePattern(), c.Writer.Status())
+		},
+	}, nil)
+
+	if out != "[API] /users/:id 201\n" {
+		t.Errorf("Expected the Formatter to be used, got %q", out)
+	}
+}
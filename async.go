@@ -0,0 +1,269 @@
+package zen
+
+// This file contains AsyncWriter, which moves log writes off the request path:
+// lines go to a bounded buffer and a background goroutine writes them in
+// batches. When the buffer is full, a policy decides whether to block, drop
+// the line or keep a sample of the lines. The writers of the Logger middleware
+// are flushed and closed when the Engine serving it shuts down.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Defaults of AsyncConfig
+const (
+	DefaultAsyncBufferSize = 1024
+	DefaultAsyncBatchSize  = 64
+	DefaultAsyncSampleRate = 10
+)
+
+// FullPolicy decides what happens to a line written while the buffer is full
+type FullPolicy int
+
+const (
+	// BlockWhenFull waits for room in the buffer, so no line is lost
+	BlockWhenFull FullPolicy = iota
+	// DropWhenFull discards the line, so logging never slows requests down
+	DropWhenFull
+	// SampleWhenFull keeps one line in SampleRate once the buffer is three
+	// quarters full, so the log still shows a sample of the traffic while it
+	// catches up, and discards lines that find it full
+	SampleWhenFull
+)
+
+// AsyncConfig configures an AsyncWriter
+type AsyncConfig struct {
+	// Lines buffered before the policy applies, DefaultAsyncBufferSize by default
+	BufferSize int
+
+	// Most lines written to the underlying writer at once, DefaultAsyncBatchSize by default
+	BatchSize int
+
+	// What to do when the buffer is full, BlockWhenFull by default
+	Policy FullPolicy
+
+	// With SampleWhenFull, one line in SampleRate is kept under pressure, DefaultAsyncSampleRate by default
+	SampleRate int
+}
+
+// asyncEntry is a buffered line, or a flush request when flushed is set
+type asyncEntry struct {
+	line    []byte
+	flushed chan struct{}
+}
+
+// AsyncWriter is an io.Writer that buffers writes and passes them to another
+// writer from a background goroutine, batching the lines that piled up.
+// Write returns before the line is written, so errors of the underlying writer
+// are not reported. Each Write is kept as one line; it is safe for concurrent use.
+type AsyncWriter struct {
+	w      io.Writer
+	config AsyncConfig
+	queue  chan asyncEntry
+	done   chan struct{}
+
+	mu     sync.RWMutex // held for reading while queueing, for writing by Close
+	closed bool
+
+	written  atomic.Uint64
+	dropped  atomic.Uint64
+	pressure atomic.Uint64 // lines written while the buffer was nearly full, for sampling
+}
+
+var (
+	asyncWriters   = make(map[*AsyncWriter]struct{}) // the open writers, flushed by Flush
+	asyncWritersMu sync.Mutex
+)
+
+// NewAsyncWriter returns a writer buffering writes to w. Close it to stop its
+// goroutine; Flush writes out what is buffered.
+//
+// Usage:
+//
+//	out := zen.NewAsyncWriter(file, zen.AsyncConfig{Policy: zen.DropWhenFull})
+//	app.SetLogHandler(zen.NewJSONHandler(out, nil))
+func NewAsyncWriter(w io.Writer, config AsyncConfig) *AsyncWriter {
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultAsyncBufferSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultAsyncBatchSize
+	}
+	if config.SampleRate <= 0 {
+		config.SampleRate = DefaultAsyncSampleRate
+	}
+
+	a := &AsyncWriter{
+		w:      w,
+		config: config,
+		queue:  make(chan asyncEntry, config.BufferSize),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+
+	asyncWritersMu.Lock()
+	asyncWriters[a] = struct{}{}
+	asyncWritersMu.Unlock()
+	return a
+}
+
+// Write queues a copy of p. When the buffer is full, the policy of the writer
+// decides whether it waits or drops p; a dropped line is not an error. After
+// Close, p is written directly.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return a.w.Write(p)
+	}
+
+	if a.config.Policy == SampleWhenFull && len(a.queue) >= cap(a.queue)*3/4 {
+		if (a.pressure.Add(1)-1)%uint64(a.config.SampleRate) != 0 {
+			a.dropped.Add(1)
+			return len(p), nil
+		}
+	}
+
+	entry := asyncEntry{line: bytes.Clone(p)}
+	if a.config.Policy == BlockWhenFull {
+		a.queue <- entry
+		return len(p), nil
+	}
+	select {
+	case a.queue <- entry:
+	default:
+		a.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Flush waits until the lines written so far have been passed to the underlying writer
+func (a *AsyncWriter) Flush() error {
+	return a.flush(context.Background())
+}
+
+func (a *AsyncWriter) flush(ctx context.Context) error {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return nil
+	}
+	flushed := make(chan struct{})
+	select {
+	case a.queue <- asyncEntry{flushed: flushed}:
+		a.mu.RUnlock()
+	case <-ctx.Done():
+		a.mu.RUnlock()
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes out the buffered lines and stops the writer's goroutine. It
+// doesn't close the underlying writer.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+	<-a.done
+
+	asyncWritersMu.Lock()
+	delete(asyncWriters, a)
+	asyncWritersMu.Unlock()
+	return nil
+}
+
+// Written returns the number of lines passed to the underlying writer
+func (a *AsyncWriter) Written() uint64 {
+	return a.written.Load()
+}
+
+// Dropped returns the number of lines discarded because the buffer was full
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// run writes the queued lines, batching those that are already waiting
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	var batch bytes.Buffer
+	for entry := range a.queue {
+		var flushed []chan struct{}
+		lines := 0
+		for {
+			if entry.flushed != nil {
+				flushed = append(flushed, entry.flushed)
+			} else {
+				batch.Write(entry.line)
+				lines++
+			}
+			if lines >= a.config.BatchSize {
+				break
+			}
+			next, ok := a.nextEntry()
+			if !ok {
+				break
+			}
+			entry = next
+		}
+
+		if batch.Len() > 0 {
+			a.w.Write(batch.Bytes())
+			a.written.Add(uint64(lines))
+			batch.Reset()
+		}
+		for _, ch := range flushed {
+			close(ch)
+		}
+	}
+}
+
+// nextEntry returns a queued entry without waiting
+func (a *AsyncWriter) nextEntry() (asyncEntry, bool) {
+	select {
+	case entry, ok := <-a.queue:
+		return entry, ok
+	default:
+		return asyncEntry{}, false
+	}
+}
+
+// Flush writes out the lines buffered by every AsyncWriter in the process,
+// waiting at most until ctx is done. An Engine shutting down only flushes the
+// writers of its own Logger middleware.
+func Flush(ctx context.Context) error {
+	asyncWritersMu.Lock()
+	writers := make([]*AsyncWriter, 0, len(asyncWriters))
+	for a := range asyncWriters {
+		writers = append(writers, a)
+	}
+	asyncWritersMu.Unlock()
+
+	errs := make([]error, len(writers))
+	var wg sync.WaitGroup
+	for i, a := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = a.flush(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,221 @@
+package zen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter blocks writes until its gate is opened and records them
+type gatedWriter struct {
+	gate chan struct{}
+
+	mu     sync.Mutex
+	writes []string
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.Join(w.writes, "")
+}
+
+// fill writes n numbered lines to a
+func fill(a *AsyncWriter, n int) {
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(a, "line %d\n", i)
+	}
+}
+
+func TestAsyncWriter_Batches(t *testing.T) {
+	w := newGatedWriter()
+	a := NewAsyncWriter(w, AsyncConfig{BufferSize: 16, BatchSize: 4})
+	defer a.Close()
+
+	// The first line is taken while the writer is blocked, the others pile up
+	fmt.Fprint(a, "line 0\n")
+	time.Sleep(10 * time.Millisecond)
+	for i := 1; i < 10; i++ {
+		fmt.Fprintf(a, "line %d\n", i)
+	}
+	close(w.gate)
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.writes) != 4 || w.writes[1] != "line 1\nline 2\nline 3\nline 4\n" {
+		t.Errorf("Expected the waiting lines to be written in batches of 4, got %q", w.writes)
+	}
+	if a.Written() != 10 || a.Dropped() != 0 {
+		t.Errorf("Expected 10 lines written and none dropped, got %d and %d", a.Written(), a.Dropped())
+	}
+}
+
+func TestAsyncWriter_Policies(t *testing.T) {
+	tests := []struct {
+		policy  FullPolicy
+		dropped uint64
+		last    string
+	}{
+		// 25 lines into a buffer of 8 while the writer is blocked
+		{DropWhenFull, 17, "line 7\n"},
+		// From the 7th line on, 1 in 10 is kept: the 7th, then the 17th fills the buffer
+		{SampleWhenFull, 17, "line 16\n"},
+	}
+	for _, tt := range tests {
+		w := newGatedWriter()
+		a := NewAsyncWriter(w, AsyncConfig{BufferSize: 8, Policy: tt.policy, SampleRate: 10})
+
+		fmt.Fprint(a, "taken\n")
+		time.Sleep(10 * time.Millisecond)
+		fill(a, 25)
+		close(w.gate)
+		a.Close()
+
+		if a.Dropped() != tt.dropped || a.Written()+a.Dropped() != 26 {
+			t.Errorf("Policy %d: expected %d lines dropped, got %d dropped and %d written", tt.policy, tt.dropped, a.Dropped(), a.Written())
+		}
+		if !strings.HasSuffix(w.String(), tt.last) {
+			t.Errorf("Policy %d: expected %q to be the last line kept, got %q", tt.policy, tt.last, w.String())
+		}
+	}
+}
+
+func TestAsyncWriter_Block(t *testing.T) {
+	w := newGatedWriter()
+	a := NewAsyncWriter(w, AsyncConfig{BufferSize: 2})
+
+	written := make(chan struct{})
+	go func() {
+		fill(a, 10)
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("Expected writes to block while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.gate)
+	<-written
+	a.Close()
+	if a.Written() != 10 || !strings.HasSuffix(w.String(), "line 9\n") {
+		t.Errorf("Expected every line in order, got %q", w.String())
+	}
+
+	// After Close lines are written directly
+	fmt.Fprint(a, "late\n")
+	if !strings.HasSuffix(w.String(), "late\n") {
+		t.Error("Expected writes after Close to go to the underlying writer")
+	}
+}
+
+func TestFlush_Deadline(t *testing.T) {
+	w := newGatedWriter()
+	a := NewAsyncWriter(w, AsyncConfig{})
+	defer func() {
+		close(w.gate)
+		a.Close()
+	}()
+	fmt.Fprint(a, "stuck\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestLogger_AsyncFlushedOnShutdown(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	engine := New()
+	engine.Apply(Logger(LoggerConfig{Format: "${method} ${path}", Async: &AsyncConfig{}}))
+	engine.GET("/", func(c *Context) {
+		c.Text(http.StatusOK, "ok")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, result := runEngine(t, engine, ctx)
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != "GET /\n" {
+		t.Errorf("Expected the access log to be flushed on shutdown, got %q", out.String())
+	}
+}
+
+func TestAccessLogger_ClosedByItsEngine(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	served := func(access *AccessLogger) (context.CancelFunc, <-chan error) {
+		engine := New()
+		engine.Apply(access.Handler())
+		engine.GET("/", func(c *Context) {
+			c.Text(http.StatusOK, "ok")
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		addr, result := runEngine(t, engine, ctx)
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return cancel, result
+	}
+
+	first := NewAccessLogger(LoggerConfig{Async: &AsyncConfig{}})
+	second := NewAccessLogger(LoggerConfig{Async: &AsyncConfig{}})
+	stopFirst, firstResult := served(first)
+	stopSecond, secondResult := served(second)
+
+	stopFirst()
+	if err := <-firstResult; err != nil {
+		t.Fatal(err)
+	}
+	if !first.async[0].closed || second.async[0].closed {
+		t.Error("Expected shutting down an engine to close only the writers of its own middleware")
+	}
+	if first.Written() != 1 || first.Dropped() != 0 {
+		t.Errorf("Expected 1 line written and none dropped, got %d and %d", first.Written(), first.Dropped())
+	}
+
+	stopSecond()
+	if err := <-secondResult; err != nil {
+		t.Fatal(err)
+	}
+	if !second.async[0].closed {
+		t.Error("Expected the second engine to close its writers")
+	}
+}
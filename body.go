@@ -0,0 +1,145 @@
+package zen
+
+// This file contains request body handling. Every request body is wrapped in a
+// reader that enforces the body limit in effect for the request, which is the
+// Engine's limit unless a route or group overrides it, the route taking precedence. Requests whose
+// Content-Length is already too large are rejected with 413 before the handler
+// runs. c.Body() reads the body once and caches it so middleware such as
+// signature verification or audit logging can read it again.
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+var (
+	ErrBodyTooLarge = errors.New("request body too large")
+)
+
+// SetBodyLimit sets the default maximum request body size in bytes for every
+// route. Routes and groups can override it. Zero or less means no limit.
+//
+// Usage:
+//
+//	app.SetBodyLimit(1 << 20) // 1MB
+func (engine *Engine) SetBodyLimit(limit int64) {
+	engine.bodyLimit = limit
+}
+
+// BodyLimit overrides the Engine's body limit for this route, and the limit of
+// the BodyLimit middleware applied to its group.
+//
+// Usage:
+//
+//	app.POST("/uploads", upload).BodyLimit(100 << 20) // 100MB
+func (r *RouteRef) BodyLimit(limit int64) *RouteRef {
+	router := r.engine.router
+	if router.bodyLimits[r.Method] == nil {
+		router.bodyLimits[r.Method] = make(map[string]int64)
+	}
+	router.bodyLimits[r.Method][r.Path] = limit
+	return r
+}
+
+// BodyLimit returns a middleware that overrides the body limit for the routes
+// it is applied to, typically a group. Routes with a limit of their own, set
+// with RouteRef.BodyLimit, keep it.
+//
+// Usage:
+//
+//	api := app.GroupRoutes("/api")
+//	api.Apply(zen.BodyLimit(64 << 10))
+func BodyLimit(limit int64) HandlerFunc {
+	return func(c *Context) {
+		if !c.routeLimit {
+			c.bodyLimit = limit
+		}
+		if c.contentTooLarge() {
+			c.writeTooLarge()
+			c.Quit()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Body returns the request body. The body is read once and cached, and
+// c.Request.Body is reset after every call so it can be read again by
+// middleware or handlers that use it directly.
+//
+// Usage:
+//
+//	body, err := c.Body()
+//	if errors.Is(err, zen.ErrBodyTooLarge) {
+//	    c.Error(http.StatusRequestEntityTooLarge, "Body too large")
+//	    return
+//	}
+//	if !verifySignature(body, c.GetHeader("X-Signature")) { ... }
+func (c *Context) Body() ([]byte, error) {
+	if !c.bodyCached {
+		if c.Request.Body == nil {
+			c.body = []byte{}
+		} else {
+			data, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				return nil, err
+			}
+			c.body = data
+		}
+		c.bodyCached = true
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(c.body))
+	return c.body, nil
+}
+
+// contentTooLarge reports whether the declared Content-Length exceeds the body limit
+func (c *Context) contentTooLarge() bool {
+	return c.bodyLimit > 0 && c.Request.ContentLength > c.bodyLimit
+}
+
+// writeTooLarge responds with 413 and asks the client to close the connection,
+// since the rest of the body will not be read
+func (c *Context) writeTooLarge() {
+	c.SetHeader("Connection", "close")
+	c.Text(http.StatusRequestEntityTooLarge, "413 REQUEST ENTITY TOO LARGE")
+}
+
+// limitedBody enforces the body limit of its Context on every read, so a
+// limit set by a route or middleware applies even after the body was wrapped
+type limitedBody struct {
+	c    *Context
+	rc   io.ReadCloser
+	read int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	limit := b.c.bodyLimit
+	if limit <= 0 {
+		n, err := b.rc.Read(p)
+		b.read += int64(n)
+		return n, err
+	}
+
+	remaining := limit - b.read
+	if remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	// Read one byte past the limit to detect bodies that exceed it
+	if int64(len(p)) > remaining+1 {
+		p = p[:remaining+1]
+	}
+	n, err := b.rc.Read(p)
+	if int64(n) > remaining {
+		b.read = limit + 1
+		return int(remaining), ErrBodyTooLarge
+	}
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
@@ -0,0 +1,145 @@
+package zen
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	engine := New()
+	engine.SetBodyLimit(10)
+
+	echo := func(c *Context) {
+		body, err := c.Body()
+		if err != nil {
+			c.Text(http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		c.Text(http.StatusOK, string(body))
+	}
+	engine.POST("/small", echo)
+	engine.POST("/upload", echo).BodyLimit(100)
+
+	api := engine.GroupRoutes("/api")
+	api.Apply(BodyLimit(5))
+	api.POST("/tiny", echo)
+	api.POST("/bulk", echo).BodyLimit(50)
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		chunked  bool
+		expected int
+	}{
+		{name: "within engine limit", path: "/small", body: "hello", expected: http.StatusOK},
+		{name: "over engine limit", path: "/small", body: strings.Repeat("a", 11), expected: http.StatusRequestEntityTooLarge},
+		{name: "route override", path: "/upload", body: strings.Repeat("a", 50), expected: http.StatusOK},
+		{name: "over route limit", path: "/upload", body: strings.Repeat("a", 101), expected: http.StatusRequestEntityTooLarge},
+		{name: "group middleware", path: "/api/tiny", body: "hello!", expected: http.StatusRequestEntityTooLarge},
+		{name: "route over group", path: "/api/bulk", body: strings.Repeat("a", 50), expected: http.StatusOK},
+		{name: "over route in group", path: "/api/bulk", body: strings.Repeat("a", 51), expected: http.StatusRequestEntityTooLarge},
+		{name: "chunked within limit", path: "/small", body: "hello", chunked: true, expected: http.StatusOK},
+		{name: "chunked over limit", path: "/small", body: strings.Repeat("a", 11), chunked: true, expected: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				// Without a Content-Length the limit is enforced while reading
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+			}
+			if tt.expected == http.StatusOK && w.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestContext_Body(t *testing.T) {
+	engine := New()
+
+	var seen []byte
+	engine.Apply(func(c *Context) {
+		// e.g. a signature check reading the body before the handler
+		body, err := c.Body()
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen = body
+		c.Next()
+	})
+
+	engine.POST("/users", func(c *Context) {
+		var user struct {
+			Name string `json:"name"`
+		}
+		if err := c.ParseJSON(&user); err != nil {
+			c.Text(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// The body can still be read directly
+		raw, _ := io.ReadAll(c.Request.Body)
+		c.Text(http.StatusOK, user.Name+" "+string(raw))
+	})
+
+	body := `{"name":"John"}`
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/users", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if expected := "John " + body; w.Body.String() != expected {
+		t.Errorf("Expected body %q, got %q", expected, w.Body.String())
+	}
+	if !bytes.Equal(seen, []byte(body)) {
+		t.Errorf("Expected middleware to see %q, got %q", body, seen)
+	}
+}
+
+func TestContext_ParseJSONTooLarge(t *testing.T) {
+	engine := New()
+	engine.SetBodyLimit(8)
+	engine.POST("/users", func(c *Context) {
+		var user map[string]interface{}
+		c.ParseJSONWithError(&user)
+	})
+
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"John"}`))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestContext_ParseJSONTrailingData(t *testing.T) {
+	for _, body := range []string{`{"name":"John"} garbage`, `{"name":"John"}{"name":"Jane"}`} {
+		c := NewContext(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		var user map[string]interface{}
+		if err := c.ParseJSON(&user); err != ErrBadJSON {
+			t.Errorf("Expected %v for %q, got %v", ErrBadJSON, body, err)
+		}
+	}
+
+	c := NewContext(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("{\"name\":\"John\"}\n")))
+	var user map[string]interface{}
+	if err := c.ParseJSON(&user); err != nil || user["name"] != "John" {
+		t.Errorf("Expected trailing whitespace to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package zen
+
+// This file contains conditional request handling (RFC 9110 section 13).
+// c.SetETag and c.SetLastModified set the validators of a response and answer
+// 304 Not Modified when the client's cached copy is current, or 412
+// Precondition Failed when an If-Match or If-Unmodified-Since precondition of
+// an update does not hold. The ETag middleware buffers GET responses and
+// derives an ETag from the body for handlers that don't set one.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagConfig holds the configuration for the ETag middleware
+type ETagConfig struct {
+	// Weak generates weak ETags (W/"..."), for responses that are equivalent
+	// but not byte for byte identical, e.g. compressed differently.
+	Weak bool
+
+	// MaxSize is the largest body in bytes that is buffered and hashed. Larger
+	// responses are streamed without an ETag. Default 1MB.
+	MaxSize int
+}
+
+// DefaultETagConfig returns the default ETag configuration
+func DefaultETagConfig() ETagConfig {
+	return ETagConfig{
+		MaxSize: 1 << 20, // 1MB
+	}
+}
+
+// ETag returns a middleware that sets an ETag on successful GET responses by
+// hashing their body, and answers 304 Not Modified when it matches the
+// request's If-None-Match header. HEAD responses have no body to hash, so they
+// only carry an ETag set by the handler with SetETag.
+//
+// Usage:
+//
+//	app.Apply(zen.ETag())
+//
+//	// or with weak ETags
+//	app.Apply(zen.ETag(zen.ETagConfig{Weak: true}))
+func ETag(config ...ETagConfig) HandlerFunc {
+	cfg := DefaultETagConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultETagConfig().MaxSize
+	}
+
+	return func(c *Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		original := c.Writer.ResponseWriter
+		buffer := &etagWriter{ResponseWriter: original, maxSize: cfg.MaxSize}
+		c.Writer.ResponseWriter = buffer
+		c.Next()
+		c.Writer.ResponseWriter = original
+
+		if buffer.passthrough || c.Writer.Hijacked() || !c.Writer.Written() {
+			return
+		}
+
+		status := buffer.status
+		header := original.Header()
+		if status == http.StatusOK && header.Get("ETag") == "" {
+			sum := sha256.Sum256(buffer.buf.Bytes())
+			etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+			if cfg.Weak {
+				etag = "W/" + etag
+			}
+			header.Set("ETag", etag)
+
+			if etagMatches(c.GetHeader("If-None-Match"), etag, false) {
+				header.Del("Content-Length")
+				c.Writer.StatusCode = http.StatusNotModified
+				original.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		original.WriteHeader(status)
+		original.Write(buffer.buf.Bytes())
+	}
+}
+
+// SetETag sets the ETag of the response and evaluates the If-Match and
+// If-None-Match preconditions against it. If a precondition decides the
+// response, 304 Not Modified or 412 Precondition Failed is written and true is
+// returned, and the handler should return without writing a body.
+// An unquoted etag is quoted; prefix it with W/ for a weak ETag.
+//
+// Usage:
+//
+//	app.GET("/articles/:id", func(c *zen.Context) {
+//	    article := load(c.GetParam("id"))
+//	    if c.SetETag(article.Version) {
+//	        return
+//	    }
+//	    c.JSON(http.StatusOK, article)
+//	})
+//
+//	// optimistic concurrency: clients send If-Match with the ETag they read
+//	app.PUT("/articles/:id", func(c *zen.Context) {
+//	    article := load(c.GetParam("id"))
+//	    if c.SetETag(article.Version) {
+//	        return // 412 if the article changed since the client read it
+//	    }
+//	    ...
+//	})
+func (c *Context) SetETag(etag string) bool {
+	etag = quoteETag(etag)
+	c.SetHeader("ETag", etag)
+
+	if match := c.GetHeader("If-Match"); match != "" && !etagMatches(match, etag, true) {
+		c.preconditionFailed()
+		return true
+	}
+	if noneMatch := c.GetHeader("If-None-Match"); noneMatch != "" && etagMatches(noneMatch, etag, false) {
+		if c.isSafeMethod() {
+			c.notModified()
+		} else {
+			c.preconditionFailed()
+		}
+		return true
+	}
+	return false
+}
+
+// SetLastModified sets the Last-Modified time of the response and evaluates
+// the If-Unmodified-Since and If-Modified-Since preconditions against it. They
+// are ignored when the request has If-Match or If-None-Match respectively, as
+// ETags are the more precise validator. If a precondition decides the response,
+// 304 Not Modified or 412 Precondition Failed is written and true is returned.
+//
+// Usage:
+//
+//	if c.SetLastModified(article.UpdatedAt) {
+//	    return
+//	}
+func (c *Context) SetLastModified(modtime time.Time) bool {
+	// HTTP dates have a resolution of one second
+	modtime = modtime.UTC().Truncate(time.Second)
+	if modtime.IsZero() {
+		return false
+	}
+	c.SetHeader("Last-Modified", modtime.Format(http.TimeFormat))
+
+	if c.GetHeader("If-Match") == "" {
+		if since, err := http.ParseTime(c.GetHeader("If-Unmodified-Since")); err == nil && modtime.After(since) {
+			c.preconditionFailed()
+			return true
+		}
+	}
+	if c.GetHeader("If-None-Match") == "" && c.isSafeMethod() {
+		if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !modtime.After(since) {
+			c.notModified()
+			return true
+		}
+	}
+	return false
+}
+
+// isSafeMethod reports whether the request method is GET or HEAD
+func (c *Context) isSafeMethod() bool {
+	return c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+}
+
+// notModified writes a 304 response without content headers
+func (c *Context) notModified() {
+	header := c.Writer.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	c.Writer.WriteHeader(http.StatusNotModified)
+}
+
+// preconditionFailed writes a 412 response
+func (c *Context) preconditionFailed() {
+	c.Text(http.StatusPreconditionFailed, "412 PRECONDITION FAILED")
+}
+
+// quoteETag quotes an ETag unless it is already quoted
+func quoteETag(etag string) string {
+	weak := strings.HasPrefix(etag, "W/")
+	opaque := strings.TrimPrefix(etag, "W/")
+	if !strings.HasPrefix(opaque, `"`) || !strings.HasSuffix(opaque, `"`) || len(opaque) < 2 {
+		opaque = `"` + opaque + `"`
+	}
+	if weak {
+		return "W/" + opaque
+	}
+	return opaque
+}
+
+// etagMatches reports whether etag matches an If-Match or If-None-Match header.
+// Strong comparison, used for If-Match, never matches weak ETags.
+func etagMatches(header, etag string, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+
+	for header != "" {
+		header = strings.TrimLeft(header, " \t,")
+		weak := strings.HasPrefix(header, "W/")
+		header = strings.TrimPrefix(header, "W/")
+		if !strings.HasPrefix(header, `"`) {
+			return false
+		}
+		end := strings.IndexByte(header[1:], '"')
+		if end < 0 {
+			return false
+		}
+		candidate := header[:end+2]
+		header = header[end+2:]
+
+		if candidate == opaque && !(strong && weak) {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter buffers a response so the ETag middleware can hash it. Responses
+// that are flushed or grow past maxSize are passed through unbuffered.
+type etagWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	maxSize     int
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.buf.Len()+len(data) > w.maxSize {
+		if err := w.stopBuffering(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+// Flush stops buffering, since a flushing handler is streaming
+func (w *etagWriter) Flush() {
+	if !w.passthrough {
+		w.stopBuffering()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, for use with http.ResponseController
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.passthrough = true
+	return hijacker.Hijack()
+}
+
+// stopBuffering writes the buffered response and passes later writes through
+func (w *etagWriter) stopBuffering() error {
+	w.passthrough = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
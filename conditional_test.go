@@ -0,0 +1,162 @@
+package zen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETagMiddleware(t *testing.T) {
+	for _, weak := range []bool{false, true} {
+		engine := New()
+		engine.Apply(ETag(ETagConfig{Weak: weak, MaxSize: 64}))
+		engine.GET("/articles", func(c *Context) {
+			c.JSON(http.StatusOK, M{"title": "hello"})
+		})
+		engine.GET("/large", func(c *Context) {
+			c.Text(http.StatusOK, strings.Repeat("a", 100))
+		})
+
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/articles", nil))
+		etag := w.Header().Get("ETag")
+		if w.Code != http.StatusOK || etag == "" || w.Body.Len() == 0 {
+			t.Fatalf("Expected 200 with an ETag, got %d %q", w.Code, etag)
+		}
+		if strings.HasPrefix(etag, "W/") != weak {
+			t.Errorf("Expected weak=%v ETag, got %q", weak, etag)
+		}
+
+		req := httptest.NewRequest("GET", "/articles", nil)
+		req.Header.Set("If-None-Match", `"other", `+etag)
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("Expected 304 without body, got %d with %d bytes", w.Code, w.Body.Len())
+		}
+
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/large", nil))
+		if w.Header().Get("ETag") != "" || w.Body.Len() != 100 {
+			t.Errorf("Responses over MaxSize should be streamed without ETag, got %q with %d bytes", w.Header().Get("ETag"), w.Body.Len())
+		}
+	}
+}
+
+func TestETagMiddleware_HeadAndResponseController(t *testing.T) {
+	engine := New()
+	engine.Apply(ETag())
+	engine.HEAD("/articles", func(c *Context) {
+		c.SetHeader("Content-Length", "17")
+		c.Status(http.StatusOK)
+	})
+	engine.GET("/stream", func(c *Context) {
+		// The middleware's writer must unwrap to the connection's
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(time.Minute)); err != nil {
+			c.Text(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Text(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("HEAD", "/articles", nil))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != "" {
+		t.Errorf("Expected HEAD to get no ETag hashed from its empty body, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == "" {
+		t.Errorf("Expected the ResponseController to reach the connection, got %d", resp.StatusCode)
+	}
+}
+
+func TestContext_SetETag(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		etag     string
+		headers  map[string]string
+		expected int
+	}{
+		{name: "no preconditions", method: "GET", etag: "v1", expected: http.StatusOK},
+		{name: "if-none-match", method: "GET", etag: "v1", headers: map[string]string{"If-None-Match": `"v1"`}, expected: http.StatusNotModified},
+		{name: "if-none-match weak", method: "GET", etag: "v1", headers: map[string]string{"If-None-Match": `W/"v1"`}, expected: http.StatusNotModified},
+		{name: "if-none-match changed", method: "GET", etag: "v2", headers: map[string]string{"If-None-Match": `"v1"`}, expected: http.StatusOK},
+		{name: "if-match", method: "PUT", etag: "v1", headers: map[string]string{"If-Match": `"v1"`}, expected: http.StatusOK},
+		{name: "if-match changed", method: "PUT", etag: "v2", headers: map[string]string{"If-Match": `"v1"`}, expected: http.StatusPreconditionFailed},
+		{name: "if-match weak", method: "PATCH", etag: `W/"v1"`, headers: map[string]string{"If-Match": `W/"v1"`}, expected: http.StatusPreconditionFailed},
+		{name: "if-match any", method: "PUT", etag: "v1", headers: map[string]string{"If-Match": "*"}, expected: http.StatusOK},
+		{name: "if-none-match on update", method: "PUT", etag: "v1", headers: map[string]string{"If-None-Match": "*"}, expected: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/articles/1", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			c := NewContext(w, req)
+
+			if !c.SetETag(tt.etag) {
+				c.Text(http.StatusOK, "article")
+			}
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+			}
+			if w.Header().Get("ETag") != quoteETag(tt.etag) {
+				t.Errorf("Expected ETag %q, got %q", quoteETag(tt.etag), w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestContext_SetLastModified(t *testing.T) {
+	modtime := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	before := modtime.Add(-time.Hour).Format(http.TimeFormat)
+	at := modtime.Format(http.TimeFormat)
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		expected int
+	}{
+		{name: "no preconditions", method: "GET", expected: http.StatusOK},
+		{name: "not modified", method: "GET", headers: map[string]string{"If-Modified-Since": at}, expected: http.StatusNotModified},
+		{name: "modified", method: "GET", headers: map[string]string{"If-Modified-Since": before}, expected: http.StatusOK},
+		{name: "if-none-match takes precedence", method: "GET", headers: map[string]string{"If-Modified-Since": at, "If-None-Match": `"v0"`}, expected: http.StatusOK},
+		{name: "unmodified", method: "PUT", headers: map[string]string{"If-Unmodified-Since": at}, expected: http.StatusOK},
+		{name: "modified since read", method: "PUT", headers: map[string]string{"If-Unmodified-Since": before}, expected: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/articles/1", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			c := NewContext(w, req)
+
+			if !c.SetLastModified(modtime) {
+				c.Text(http.StatusOK, "article")
+			}
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+			}
+			if w.Header().Get("Last-Modified") != at {
+				t.Errorf("Expected Last-Modified %q, got %q", at, w.Header().Get("Last-Modified"))
+			}
+		})
+	}
+}
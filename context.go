@@ -23,6 +23,26 @@ type Context struct {
 	Handlers []HandlerFunc     // Slice of middleware functions
 	Index    int               // Current position in the middleware chain
 	Ctx      context.Context
+	engine   *Engine // The engine serving this request, nil for standalone contexts
+	route    string  // The pattern of the matched route, e.g. "/users/:id"
+
+	requestID string // see RequestID
+	userID    string // see UserID
+	logger    *Log   // see Logger, nil until first used
+
+	outgoingFlashes []FlashMessage // flash messages added during this request
+	flashesRead     bool           // whether the incoming flash messages were consumed
+
+	bodyLimit  int64  // maximum request body size in bytes, 0 for none
+	routeLimit bool   // whether bodyLimit was set by the route, which middleware doesn't override
+	body       []byte // the cached request body, see Body
+	bodyCached bool   // whether body holds the request body
+
+	upload       *UploadConfig // upload limits for this request, see uploadConfig
+	allowedTypes []string      // file types allowed by middleware, see SetAllowedFileTypes
+	uploadForm   *UploadForm   // the cached multipart form, see MultipartForm
+	uploadErr    error         // the error of parsing the multipart form
+	cleanups     []func()      // run when the request ends, e.g. to remove temporary files
 }
 
 // newContext creates a new Context instance
@@ -155,44 +175,51 @@ func (c *Context) Status(code int) {
 	c.Writer.WriteHeader(code)
 }
 
-// GetClientIP returns the client IP address
+// GetClientIP returns the client IP address without the port.
+// Forwarding headers are only used when the request comes from a proxy
+// trusted with Engine.SetTrustedProxies, or a platform header was opted
+// into with Engine.SetTrustedPlatform.
 func (c *Context) GetClientIP() string {
-	// First, we need to check X-Real-IP header
-	ip := c.GetHeader("X-Real-IP")
-	if ip != "" {
-		return ip
-	}
-
-	// check X-Forwarded-For header
-	ip = c.GetHeader("X-Forwarded-For")
-	if ip != "" {
-		return ip
-	}
-
-	// Get the ip for RemoteAddr
-	return c.Request.RemoteAddr
+	return c.clientIP()
 }
 
-// ParseJSON parses request body into the provided struct
+// ParseJSON parses request body into the provided struct.
+// A body cached with Body is decoded from the cache, otherwise the body is
+// decoded as it is read without holding it in memory.
 func (c *Context) ParseJSON(obj interface{}) error {
-	if c.Request.Body == nil {
-		return ErrEmptyBody
+	if c.bodyCached {
+		if len(c.body) == 0 {
+			return ErrEmptyBody
+		}
+		if err := json.Unmarshal(c.body, obj); err != nil {
+			return ErrBadJSON
+		}
+		return nil
 	}
 
-	body, err := io.ReadAll(c.Request.Body)
-	defer c.Request.Body.Close()
-	if err != nil {
-		return err
+	if c.Request.Body == nil {
+		return ErrEmptyBody
 	}
 
-	if len(body) == 0 {
-		return ErrEmptyBody
+	dec := json.NewDecoder(c.Request.Body)
+	if err := dec.Decode(obj); err != nil {
+		switch {
+		case errors.Is(err, io.EOF):
+			return ErrEmptyBody
+		case errors.Is(err, ErrBodyTooLarge):
+			return ErrBodyTooLarge
+		default:
+			return ErrBadJSON
+		}
 	}
 
-	if err := json.Unmarshal(body, obj); err != nil {
+	// Like json.Unmarshal, reject anything after the value
+	if err := dec.Decode(&json.RawMessage{}); !errors.Is(err, io.EOF) {
+		if errors.Is(err, ErrBodyTooLarge) {
+			return ErrBodyTooLarge
+		}
 		return ErrBadJSON
 	}
-
 	return nil
 }
 
@@ -204,7 +231,11 @@ func (c *Context) TryParseJSON(obj interface{}) bool {
 // ParseJSONWithError binds JSON and writes an error response if binding fails
 func (c *Context) ParseJSONWithError(obj interface{}) bool {
 	if err := c.ParseJSON(obj); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]interface{}{
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrBodyTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, map[string]interface{}{
 			"error": err.Error(),
 		})
 		return false
@@ -217,11 +248,35 @@ func (c *Context) SetHeader(key, value string) {
 	c.Writer.Header().Set(key, value)
 }
 
+// DeclareTrailers announces trailers in the response header, before the body is written
+func (c *Context) DeclareTrailers(keys ...string) {
+	c.Writer.DeclareTrailers(keys...)
+}
+
+// SetTrailer sets a trailer sent after the response body, e.g. a checksum of a
+// streamed body or the status of a long running operation
+func (c *Context) SetTrailer(key, value string) {
+	c.Writer.SetTrailer(key, value)
+}
+
+// Push pushes target to the client with HTTP/2 server push, before the response
+// that needs it. Returns http.ErrNotSupported over HTTP/1 or if the client disabled push.
+func (c *Context) Push(target string, opts *http.PushOptions) error {
+	return c.Writer.Push(target, opts)
+}
+
 // GetHeader returns the value of a header from the request
 func (c *Context) GetHeader(key string) string {
 	return c.Request.Header.Get(key)
 }
 
+// RoutePattern returns the pattern of the route that matched the request, e.g.
+// "/users/:id", or an empty string if no route matched. Unlike the path it
+// doesn't vary with the parameters, which suits logs and metrics.
+func (c *Context) RoutePattern() string {
+	return c.route
+}
+
 // GetPath returns the URL path
 func (c *Context) GetURLPath() string {
 	return c.Request.URL.Path
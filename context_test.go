@@ -157,6 +157,11 @@ func TestContext_BindJSON(t *testing.T) {
 }
 
 func TestContext_ClientIP(t *testing.T) {
+	engine := New()
+	if err := engine.SetTrustedProxies("10.0.0.0/8", "2.2.2.2"); err != nil {
+		t.Fatal(err)
+	}
+
 	tests := []struct {
 		name       string
 		headers    map[string]string
@@ -185,6 +190,62 @@ func TestContext_ClientIP(t *testing.T) {
 			remoteAddr: "2.2.2.2",
 			want:       "2.2.2.2",
 		},
+		{
+			name:       "RemoteAddr with port",
+			headers:    map[string]string{},
+			remoteAddr: "[2001:db8::1]:443",
+			want:       "2001:db8::1",
+		},
+		{
+			name: "untrusted peer",
+			headers: map[string]string{
+				"X-Real-IP":       "1.1.1.1",
+				"X-Forwarded-For": "3.3.3.3",
+			},
+			remoteAddr: "4.4.4.4:1234",
+			want:       "4.4.4.4",
+		},
+		{
+			name: "X-Forwarded-For is read from the right",
+			headers: map[string]string{
+				"X-Forwarded-For": "6.6.6.6, 5.5.5.5, 10.0.0.2",
+			},
+			remoteAddr: "10.0.0.1:1234",
+			want:       "5.5.5.5",
+		},
+		{
+			name: "X-Forwarded-For with only trusted proxies",
+			headers: map[string]string{
+				"X-Forwarded-For": "10.0.0.3, 10.0.0.2",
+			},
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.3",
+		},
+		{
+			name: "Forwarded",
+			headers: map[string]string{
+				"Forwarded":       `for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.2`,
+				"X-Forwarded-For": "7.7.7.7",
+			},
+			remoteAddr: "10.0.0.1:1234",
+			want:       "2001:db8:cafe::17",
+		},
+		{
+			name: "Forwarded unknown",
+			headers: map[string]string{
+				"Forwarded": "for=unknown",
+			},
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.1",
+		},
+		{
+			name: "platform header not opted in",
+			headers: map[string]string{
+				PlatformCloudflare: "8.8.8.8",
+			},
+			remoteAddr: "4.4.4.4:1234",
+			want:       "4.4.4.4",
+		},
 	}
 
 	for _, tt := range tests {
@@ -196,11 +257,32 @@ func TestContext_ClientIP(t *testing.T) {
 			}
 
 			c := NewContext(httptest.NewRecorder(), req)
+			c.engine = engine
 			if got := c.GetClientIP(); got != tt.want {
 				t.Errorf("ClientIP() = %v, want %v", got, tt.want)
 			}
 		})
 	}
+
+	t.Run("platform header", func(t *testing.T) {
+		platform := New()
+		platform.SetTrustedPlatform(PlatformCloudflare)
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "4.4.4.4:1234"
+		req.Header.Set(PlatformCloudflare, "8.8.8.8")
+		c := NewContext(httptest.NewRecorder(), req)
+		c.engine = platform
+		if got := c.GetClientIP(); got != "8.8.8.8" {
+			t.Errorf("ClientIP() = %v, want %v", got, "8.8.8.8")
+		}
+	})
+
+	t.Run("invalid proxy", func(t *testing.T) {
+		if err := New().SetTrustedProxies("not-an-ip"); err == nil {
+			t.Error("Expected an error for an invalid proxy")
+		}
+	})
 }
 
 func TestContext_Context(t *testing.T) {
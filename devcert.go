@@ -0,0 +1,210 @@
+package zen
+
+// This file contains self-signed certificates for local HTTPS. ServeTLSDev
+// creates a local CA once, which the developer trusts in their browser, and a
+// leaf certificate for localhost signed by it. Both are cached so the CA only
+// needs to be trusted once. It refuses to run outside DevMode.
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Files in the development certificate directory
+const (
+	devCAFile      = "ca.pem"
+	devCAKeyFile   = "ca-key.pem"
+	devCertFile    = "localhost.pem"
+	devCertKeyFile = "localhost-key.pem"
+)
+
+var ErrDevCertsNotDevMode = errors.New("ServeTLSDev only runs in DevMode, use ServeTLS with a real certificate")
+
+// SetDevCertDir sets the directory ServeTLSDev caches its CA and certificate in.
+// Default "zen/devcerts" in the user cache directory, e.g. ~/.cache/zen/devcerts.
+func (engine *Engine) SetDevCertDir(dir string) {
+	engine.devCertDir = dir
+}
+
+// ServeTLSDev serves HTTPS on addr with a development certificate for localhost,
+// 127.0.0.1, ::1 and the host of addr. On first use it creates a local CA and
+// prints how to trust it; later runs reuse it, renewing the certificate when it
+// nears expiry. The certificate is added to a copy of ServerConfig.TLSConfig,
+// which is left unchanged. Returns ErrDevCertsNotDevMode outside DevMode.
+//
+// Usage:
+//
+//	app.ServeTLSDev(":8443")
+func (e *Engine) ServeTLSDev(addr string) error {
+	if e.Mode() != DevMode {
+		e.logger.Error(ErrDevCertsNotDevMode.Error())
+		return ErrDevCertsNotDevMode
+	}
+
+	dir := e.devCertDir
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(cache, "zen", "devcerts")
+	}
+
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" && !slices.Contains(hosts, host) {
+		hosts = append(hosts, host)
+	}
+
+	cert, created, err := loadDevCertificate(dir, hosts)
+	if err != nil {
+		return err
+	}
+	printTrustInstructions(filepath.Join(dir, devCAFile), created)
+
+	config := e.serverConfig.TLSConfig.Clone()
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.Certificates = []tls.Certificate{cert}
+	return e.serveTLS(addr, config, "", "")
+}
+
+// loadDevCertificate returns the cached leaf certificate for hosts, creating
+// the CA and leaf as needed. created reports whether a new CA was created.
+func loadDevCertificate(dir string, hosts []string) (cert tls.Certificate, created bool, err error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return cert, false, err
+	}
+	caFile, caKeyFile := filepath.Join(dir, devCAFile), filepath.Join(dir, devCAKeyFile)
+	certFile, keyFile := filepath.Join(dir, devCertFile), filepath.Join(dir, devCertKeyFile)
+
+	ca, caErr := tls.LoadX509KeyPair(caFile, caKeyFile)
+	if caErr == nil {
+		ca.Leaf, caErr = x509.ParseCertificate(ca.Certificate[0])
+	}
+	if caErr != nil || time.Now().After(ca.Leaf.NotAfter) {
+		ca, err = createDevCertificate(caFile, caKeyFile, nil, nil)
+		if err != nil {
+			return cert, false, err
+		}
+		created = true
+	}
+
+	cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil && validDevCertificate(cert, ca.Leaf, hosts) {
+		return cert, created, nil
+	}
+	cert, err = createDevCertificate(certFile, keyFile, &ca, hosts)
+	return cert, created, err
+}
+
+// validDevCertificate reports whether cert is signed by ca, covers hosts and
+// is valid for at least another week
+func validDevCertificate(cert tls.Certificate, ca *x509.Certificate, hosts []string) bool {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil || leaf.CheckSignatureFrom(ca) != nil || time.Now().Add(7*24*time.Hour).After(leaf.NotAfter) {
+		return false
+	}
+	for _, host := range hosts {
+		if leaf.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// createDevCertificate creates a CA when ca is nil, otherwise a leaf
+// certificate for hosts signed by ca, and writes it to certFile and keyFile
+func createDevCertificate(certFile, keyFile string, ca *tls.Certificate, hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	hostname, _ := os.Hostname()
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    time.Now().Add(-time.Hour),
+	}
+	parent, parentKey := template, any(key)
+	if ca == nil {
+		template.Subject = pkix.Name{CommonName: "zen development CA " + hostname, Organization: []string{"zen development CA"}}
+		template.NotAfter = time.Now().AddDate(10, 0, 0)
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.MaxPathLenZero = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	} else {
+		template.Subject = pkix.Name{CommonName: hosts[0], Organization: []string{"zen development certificate"}}
+		// Browsers reject leaf certificates valid for longer than 398 days
+		template.NotAfter = time.Now().AddDate(0, 0, 397)
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		for _, host := range hosts {
+			if ip := net.ParseIP(host); ip != nil {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			} else {
+				template.DNSNames = append(template.DNSNames, host)
+			}
+		}
+		parent, parentKey = ca.Leaf, ca.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// printTrustInstructions prints how to trust the development CA, in full when it was just created
+func printTrustInstructions(caFile string, created bool) {
+	if !created {
+		fmt.Printf("%sUsing the development CA %s%s\n", Yellow, caFile, Reset)
+		return
+	}
+	fmt.Printf(`%sCreated a development CA at %s%s
+Browsers will warn about the certificate until the CA is trusted:
+
+  macOS:   sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain %[2]s
+  Linux:   sudo cp %[2]s /usr/local/share/ca-certificates/zen-dev-ca.crt && sudo update-ca-certificates
+  Windows: certutil -addstore -user Root %[2]s
+  Firefox: Settings > Privacy & Security > Certificates > View Certificates > Authorities > Import
+
+Anyone with its key can create certificates this machine trusts, so keep the
+directory private and never trust the CA anywhere else.
+
+`, Yellow, caFile, Reset)
+}
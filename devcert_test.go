@@ -0,0 +1,103 @@
+package zen
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEngine_ServeTLSDev(t *testing.T) {
+	dir := t.TempDir()
+	engine := New()
+	engine.SetMode(DevMode)
+	engine.SetDevCertDir(dir)
+	config := DefaultServerConfig()
+	config.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS13}
+	engine.SetServerConfig(config)
+	engine.GET("/", func(c *Context) {
+		c.Text(http.StatusOK, "secure")
+	})
+
+	started := make(chan string, 1)
+	engine.OnStart(func(context.Context) error {
+		started <- engine.addr
+		return nil
+	})
+	go engine.ServeTLSDev("127.0.0.1:0")
+	defer engine.Shutdown(time.Second)
+
+	var addr string
+	select {
+	case addr = <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the server to start")
+	}
+
+	// A client trusting the development CA accepts the certificate
+	caPEM, err := os.ReadFile(filepath.Join(dir, devCAFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, devCAKeyFile)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the CA key to be private, got %v %v", info.Mode(), err)
+	}
+	if resp.TLS.Version != tls.VersionTLS13 || len(engine.serverConfig.TLSConfig.Certificates) != 0 {
+		t.Error("Expected the certificate to be added to a copy of the configured TLSConfig")
+	}
+}
+
+func TestLoadDevCertificate(t *testing.T) {
+	dir := t.TempDir()
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+
+	first, created, err := loadDevCertificate(dir, hosts)
+	if err != nil || !created {
+		t.Fatalf("Expected a new CA, got %v %v", created, err)
+	}
+
+	second, created, err := loadDevCertificate(dir, hosts)
+	if err != nil || created {
+		t.Fatalf("Expected the cached CA to be reused, got %v %v", created, err)
+	}
+	if string(first.Certificate[0]) != string(second.Certificate[0]) {
+		t.Error("Expected the cached certificate to be reused")
+	}
+
+	// A host the certificate doesn't cover renews it with the same CA
+	third, created, err := loadDevCertificate(dir, append(hosts, "myapp.test"))
+	if err != nil || created {
+		t.Fatalf("Expected the cached CA to be reused, got %v %v", created, err)
+	}
+	leaf, _ := x509.ParseCertificate(third.Certificate[0])
+	if err := leaf.VerifyHostname("myapp.test"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEngine_ServeTLSDevNotDevMode(t *testing.T) {
+	for _, mode := range []Mode{Production, Test} {
+		engine := New()
+		engine.SetMode(mode)
+		engine.SetDevCertDir(t.TempDir())
+		if err := engine.ServeTLSDev("127.0.0.1:0"); err != ErrDevCertsNotDevMode {
+			t.Errorf("Expected %v in %v, got %v", ErrDevCertsNotDevMode, mode, err)
+		}
+	}
+}
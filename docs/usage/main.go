@@ -10,7 +10,7 @@ import (
 func main() {
 	// create new zen instancee
 	app := zen.New()
-	zen.SetCurrentMode(zen.DevMode)
+	app.SetMode(zen.DevMode)
 
 	app.Apply(
 		middleware.DefaultCors(),
@@ -34,6 +34,6 @@ func main() {
 	})
 
 	if err := app.Serve(":8080"); err != nil {
-		zen.Fatal(err)
+		zen.Fatal("server stopped", "error", err)
 	}
 }
@@ -0,0 +1,284 @@
+package zen
+
+// This file contains the file download and streaming helpers for Context.
+// Files and seekable readers are served through http.ServeContent so that
+// Range, If-Range and conditional headers are handled, while Stream and the
+// JSON streaming helpers flush chunks to the client as they are produced.
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrIsDirectory = errors.New("path is a directory")
+)
+
+// streamFlushEvery is the number of items encoded between flushes by the JSON streaming helpers
+const streamFlushEvery = 64
+
+// File writes the file at the given path to the client. The Content-Type is
+// detected from the extension or, failing that, the file contents. Range and
+// If-Range requests are answered with 206 Partial Content.
+//
+// Usage:
+//
+//	app.GET("/reports/latest", func(c *zen.Context) {
+//	    c.File("./reports/latest.pdf")
+//	})
+func (c *Context) File(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		c.fileError(err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.fileError(err)
+		return
+	}
+	if info.IsDir() {
+		c.fileError(ErrIsDirectory)
+		return
+	}
+
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), f)
+}
+
+// FileFromFS writes the named file from fsys to the client, for example from an embed.FS.
+func (c *Context) FileFromFS(name string, fsys fs.FS) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		c.fileError(err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.fileError(err)
+		return
+	}
+	if info.IsDir() {
+		c.fileError(ErrIsDirectory)
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		// Without Seek Range requests cannot be served, so stream the whole file
+		c.DataFromReader(http.StatusOK, info.Size(), "", f, nil)
+		return
+	}
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), content)
+}
+
+// Attachment writes the file at the given path with a Content-Disposition header
+// that prompts the browser to download it as filename. If filename is empty
+// the base name of the path is used.
+//
+// Usage:
+//
+//	c.Attachment("./exports/users.csv", "users-2024.csv")
+func (c *Context) Attachment(path, filename string) {
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	c.SetContentDisposition("attachment", filename)
+	c.File(path)
+}
+
+// Inline writes the file at the given path with an inline Content-Disposition,
+// so the browser displays it while still knowing its filename.
+func (c *Context) Inline(path, filename string) {
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	c.SetContentDisposition("inline", filename)
+	c.File(path)
+}
+
+// SetContentDisposition sets the Content-Disposition header. Non-ASCII filenames
+// are encoded as described in RFC 6266.
+func (c *Context) SetContentDisposition(disposition, filename string) {
+	value := mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+	if value == "" {
+		value = disposition
+	}
+	c.SetHeader("Content-Disposition", value)
+}
+
+// Content writes the content of a seekable reader. name is used to detect the
+// Content-Type and modtime for Last-Modified; Range requests are supported.
+//
+// Usage:
+//
+//	report := bytes.NewReader(generateReport())
+//	c.SetContentDisposition("attachment", "report.pdf")
+//	c.Content("report.pdf", time.Now(), report)
+func (c *Context) Content(name string, modtime time.Time, content io.ReadSeeker) {
+	http.ServeContent(c.Writer, c.Request, name, modtime, content)
+}
+
+// DataFromReader writes the content of a reader that cannot seek. Pass a
+// negative contentLength if it is unknown. If contentType is empty it is
+// sniffed from the first 512 bytes of the reader.
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, headers map[string]string) {
+	if contentType == "" {
+		buffered := bufio.NewReaderSize(reader, 512)
+		head, _ := buffered.Peek(512)
+		contentType = http.DetectContentType(head)
+		reader = buffered
+	}
+
+	for k, v := range headers {
+		c.SetHeader(k, v)
+	}
+	c.SetContentType(contentType)
+	if contentLength >= 0 {
+		c.SetHeader("Content-Length", strconv.FormatInt(contentLength, 10))
+	}
+	c.Writer.WriteHeader(code)
+
+	if c.GetMethod() == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(c.Writer, reader); err != nil {
+		c.Logger().Debug("failed to write response body", "error", err)
+	}
+}
+
+// Stream repeatedly calls step with the response writer, flushing after each
+// call, until step returns false or the client goes away. It returns true if
+// the client disconnected before the stream was finished.
+//
+// Usage:
+//
+//	c.Stream(func(w io.Writer) bool {
+//	    msg, ok := <-messages
+//	    if !ok {
+//	        return false
+//	    }
+//	    fmt.Fprintln(w, msg)
+//	    return true
+//	})
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	for {
+		select {
+		case <-c.Done():
+			return true
+		default:
+		}
+
+		keepOpen := step(c.Writer)
+		c.flush()
+		if !keepOpen {
+			return false
+		}
+	}
+}
+
+// StreamNDJSON writes items returned by next as newline delimited JSON until
+// next returns false or the client disconnects. It suits large result sets
+// that should not be held in memory.
+//
+// Usage:
+//
+//	rows := db.QueryUsers()
+//	c.StreamNDJSON(http.StatusOK, func() (interface{}, bool) {
+//	    if !rows.Next() {
+//	        return nil, false
+//	    }
+//	    return rows.User(), true
+//	})
+func (c *Context) StreamNDJSON(code int, next func() (interface{}, bool)) error {
+	c.SetContentType("application/x-ndjson")
+	c.Writer.WriteHeader(code)
+
+	encoder := json.NewEncoder(c.Writer)
+	for i := 1; ; i++ {
+		if err := c.Err(); err != nil {
+			return err
+		}
+		item, ok := next()
+		if !ok {
+			break
+		}
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+		if i%streamFlushEvery == 0 {
+			c.flush()
+		}
+	}
+	c.flush()
+	return nil
+}
+
+// StreamJSONArray writes items returned by next as a single JSON array without
+// holding the whole array in memory.
+func (c *Context) StreamJSONArray(code int, next func() (interface{}, bool)) error {
+	c.SetContentType("application/json")
+	c.Writer.WriteHeader(code)
+
+	if _, err := io.WriteString(c.Writer, "["); err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		if err := c.Err(); err != nil {
+			return err
+		}
+		item, ok := next()
+		if !ok {
+			break
+		}
+
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			data = append([]byte(","), data...)
+		}
+		if _, err := c.Writer.Write(data); err != nil {
+			return err
+		}
+		if (i+1)%streamFlushEvery == 0 {
+			c.flush()
+		}
+	}
+	if _, err := io.WriteString(c.Writer, "]\n"); err != nil {
+		return err
+	}
+	c.flush()
+	return nil
+}
+
+// flush sends any buffered response data to the client
+func (c *Context) flush() {
+	c.Writer.Flush()
+}
+
+// fileError writes the status matching a file access error
+func (c *Context) fileError(err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, ErrIsDirectory):
+		c.Text(http.StatusNotFound, "404 NOT FOUND")
+	case errors.Is(err, fs.ErrPermission):
+		c.Text(http.StatusForbidden, "403 FORBIDDEN")
+	default:
+		c.Logger().Error("failed to serve file", "error", err)
+		c.Text(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR")
+	}
+}
@@ -0,0 +1,148 @@
+package zen
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestContext_File(t *testing.T) {
+	path := writeTestFile(t, "hello.txt", "hello world")
+
+	t.Run("full", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c := NewContext(w, httptest.NewRequest("GET", "/file", nil))
+		c.File(path)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+			t.Errorf("Expected text/plain content type, got %q", got)
+		}
+		if w.Body.String() != "hello world" {
+			t.Errorf("Unexpected body %q", w.Body.String())
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/file", nil)
+		req.Header.Set("Range", "bytes=6-10")
+		c := NewContext(w, req)
+		c.File(path)
+
+		if w.Code != http.StatusPartialContent {
+			t.Errorf("Expected status code %d, got %d", http.StatusPartialContent, w.Code)
+		}
+		if w.Body.String() != "world" {
+			t.Errorf("Expected partial body %q, got %q", "world", w.Body.String())
+		}
+		if c.Writer.Status() != http.StatusPartialContent {
+			t.Errorf("ResponseWriter should track status %d, got %d", http.StatusPartialContent, c.Writer.Status())
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c := NewContext(w, httptest.NewRequest("GET", "/file", nil))
+		c.File(filepath.Join(t.TempDir(), "missing.txt"))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
+func TestContext_Attachment(t *testing.T) {
+	path := writeTestFile(t, "data.csv", "a,b\n1,2\n")
+
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/download", nil))
+	c.Attachment(path, "résumé.csv")
+
+	want := `attachment; filename*=utf-8''r%C3%A9sum%C3%A9.csv`
+	if got := w.Header().Get("Content-Disposition"); got != want {
+		t.Errorf("Expected Content-Disposition %q, got %q", want, got)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+		t.Errorf("Expected text/csv content type, got %q", got)
+	}
+}
+
+func TestContext_FileFromFS(t *testing.T) {
+	fsys := fstest.MapFS{"static/app.js": {Data: []byte("console.log(1)")}}
+
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/app.js", nil))
+	c.FileFromFS("static/app.js", fsys)
+
+	if w.Body.String() != "console.log(1)" {
+		t.Errorf("Unexpected body %q", w.Body.String())
+	}
+}
+
+func TestContext_Stream(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/stream", nil))
+
+	count := 0
+	disconnected := c.Stream(func(w io.Writer) bool {
+		count++
+		fmt.Fprintf(w, "chunk %d\n", count)
+		return count < 3
+	})
+
+	if disconnected {
+		t.Error("Stream should finish without disconnect")
+	}
+	if !w.Flushed {
+		t.Error("Stream should flush the response")
+	}
+	if w.Body.String() != "chunk 1\nchunk 2\nchunk 3\n" {
+		t.Errorf("Unexpected body %q", w.Body.String())
+	}
+}
+
+func TestContext_StreamJSON(t *testing.T) {
+	items := func() func() (interface{}, bool) {
+		i := 0
+		return func() (interface{}, bool) {
+			i++
+			return M{"n": i}, i <= 3
+		}
+	}
+
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/users", nil))
+	if err := c.StreamNDJSON(http.StatusOK, items()); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n" {
+		t.Errorf("Unexpected NDJSON body %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	c = NewContext(w, httptest.NewRequest("GET", "/users", nil))
+	if err := c.StreamJSONArray(http.StatusOK, items()); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "[{\"n\":1},{\"n\":2},{\"n\":3}]\n" {
+		t.Errorf("Unexpected JSON array body %q", w.Body.String())
+	}
+}
@@ -0,0 +1,260 @@
+package zen
+
+// This file contains log levels that can be changed at runtime. A logger and
+// the child loggers created from it with Named share a level table: each name
+// can have its own minimum level, and names without one inherit it from their
+// parent, e.g. "ratelimit.redis" from "ratelimit". Without any level set,
+// DEBUG messages are written in DevMode and INFO and above otherwise.
+// LogLevelHandler exposes the table over HTTP.
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ParseLogLevel parses a level name such as "debug", "info", "warn" or "ERROR"
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "SUCCESS":
+		return SUCCESS, nil
+	case "WARN", "WARNING":
+		return WARNING, nil
+	case "ERROR":
+		return ERROR, nil
+	case "FATAL":
+		return FATAL, nil
+	}
+	return INFO, fmt.Errorf("unknown log level %q", name)
+}
+
+// MarshalText implements encoding.TextMarshaler, so levels appear by name in JSON
+func (l LogLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	level, err := ParseLogLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// logLevels is the level table shared by a logger and its named children.
+// The root level is stored under the empty name.
+type logLevels struct {
+	mu     sync.RWMutex
+	levels map[string]LogLevel
+}
+
+func newLogLevels() *logLevels {
+	return &logLevels{levels: make(map[string]LogLevel)}
+}
+
+// effective returns the level of name, inherited from its closest parent
+// with a level, and whether any level applies
+func (t *logLevels) effective(name string) (LogLevel, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for {
+		if level, ok := t.levels[name]; ok {
+			return level, true
+		}
+		if name == "" {
+			return 0, false
+		}
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[:i]
+		} else {
+			name = ""
+		}
+	}
+}
+
+func (t *logLevels) set(name string, level LogLevel) {
+	t.mu.Lock()
+	t.levels[name] = level
+	t.mu.Unlock()
+}
+
+func (t *logLevels) reset(name string) {
+	t.mu.Lock()
+	delete(t.levels, name)
+	t.mu.Unlock()
+}
+
+func (t *logLevels) snapshot() map[string]LogLevel {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	levels := make(map[string]LogLevel, len(t.levels))
+	for name, level := range t.levels {
+		levels[name] = level
+	}
+	return levels
+}
+
+// Named returns a child logger for a component. Its messages carry a "logger"
+// field with the name, and its level can be set apart from the parent's.
+// Names of nested children are joined with dots.
+//
+// Usage:
+//
+//	limiter := app.Logger().Named("ratelimit")
+//	limiter.SetLevel(zen.DEBUG) // only the rate limiter logs debug messages
+func (l *Log) Named(name string) *Log {
+	unnamed := l.handler
+	if l.name != "" {
+		name = l.name + "." + name
+		unnamed = l.unnamed
+	}
+	return &Log{
+		handler: unnamed.WithAttrs([]slog.Attr{slog.String("logger", name)}),
+		unnamed: unnamed,
+		mode:    l.mode,
+		exit:    l.exit,
+		name:    name,
+		levels:  l.levels,
+	}
+}
+
+// Name returns the name given with Named, empty for a root logger
+func (l *Log) Name() string {
+	return l.name
+}
+
+// SetLevel sets the minimum level of the logger and of the children without a
+// level of their own. It can be called at any time.
+func (l *Log) SetLevel(level LogLevel) {
+	l.levels.set(l.name, level)
+}
+
+// ResetLevel removes the level set on the logger, which inherits its parent's again
+func (l *Log) ResetLevel() {
+	l.levels.reset(l.name)
+}
+
+// Level returns the minimum level messages of the logger are written at
+func (l *Log) Level() LogLevel {
+	if level, ok := l.levels.effective(l.name); ok {
+		return level
+	}
+	if l.currentMode() == DevMode {
+		return DEBUG
+	}
+	return INFO
+}
+
+// SetLogLevel sets the minimum level of the package-level log functions
+//
+// Usage:
+//
+//	zen.SetLogLevel(zen.WARNING) // silence INFO in production
+func SetLogLevel(level LogLevel) {
+	defaultLogger().SetLevel(level)
+}
+
+// Named returns a child of the default logger for a component
+func Named(name string) *Log {
+	return defaultLogger().Named(name)
+}
+
+// logLevelUpdate is the body accepted by LogLevelHandler
+type logLevelUpdate struct {
+	Logger string `json:"logger"`
+	Level  string `json:"level"`
+}
+
+// logLevelState is the response of LogLevelHandler
+type logLevelState struct {
+	Level   LogLevel            `json:"level"`
+	Loggers map[string]LogLevel `json:"loggers"`
+}
+
+// LogLevelHandler returns an admin endpoint showing and changing the levels of
+// logger and its named children, or of the default logger if logger is nil.
+// GET returns the levels; PUT or POST with {"logger": "ratelimit", "level": "debug"}
+// sets one, an empty logger setting the level of logger itself and an empty
+// level resetting a child to its parent's. Names are relative to logger, so
+// with app.Logger().Named("db") the logger "pool" is "db.pool".
+// Protect it like any admin route.
+//
+// Usage:
+//
+//	admin := app.GroupRoutes("/admin")
+//	admin.Apply(middleware.Auth(secret))
+//	admin.GET("/log-level", zen.LogLevelHandler(app.Logger()))
+//	admin.PUT("/log-level", zen.LogLevelHandler(app.Logger()))
+//
+//	// curl -X PUT -d '{"logger":"ratelimit","level":"debug"}' .../admin/log-level
+func LogLevelHandler(logger *Log) HandlerFunc {
+	return func(c *Context) {
+		l := logger
+		if l == nil {
+			l = defaultLogger()
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			var update logLevelUpdate
+			if err := c.ParseJSON(&update); err != nil {
+				c.Error(http.StatusBadRequest, err.Error())
+				return
+			}
+			target := l
+			if update.Logger != "" {
+				target = l.Named(update.Logger)
+			}
+			if update.Level == "" {
+				if update.Logger == "" {
+					c.Error(http.StatusBadRequest, "level is required for the handler's own logger")
+					return
+				}
+				target.ResetLevel()
+				break
+			}
+			level, err := ParseLogLevel(update.Level)
+			if err != nil {
+				c.Error(http.StatusBadRequest, err.Error())
+				return
+			}
+			target.SetLevel(level)
+			l.Infof("log level of %s set to %s", loggerName(target.name), level)
+		default:
+			c.SetHeader("Allow", "GET, HEAD, PUT, POST")
+			c.Error(http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		state := logLevelState{Level: l.Level(), Loggers: make(map[string]LogLevel)}
+		for name, level := range l.levels.snapshot() {
+			if l.name != "" {
+				var ok bool
+				if name, ok = strings.CutPrefix(name, l.name+"."); !ok {
+					continue
+				}
+			}
+			if name != "" {
+				state.Loggers[name] = level
+			}
+		}
+		c.JSON(http.StatusOK, state)
+	}
+}
+
+// loggerName names a logger in messages, "root" for the empty name
+func loggerName(name string) string {
+	if name == "" {
+		return "root"
+	}
+	return name
+}
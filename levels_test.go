@@ -0,0 +1,147 @@
+package zen
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]LogLevel{
+		"debug":   DEBUG,
+		"INFO":    INFO,
+		"warn":    WARNING,
+		"Warning": WARNING,
+		" error ": ERROR,
+	}
+	for name, want := range tests {
+		if got, err := ParseLogLevel(name); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLogLevel("verbose"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
+
+func TestLog_SetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	var mode engineMode
+	mode.set(Production)
+	logger := NewLog(NewConsoleHandler(&buf, nil))
+	logger.mode = &mode
+
+	logger.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no debug messages in Production, got %q", buf.String())
+	}
+
+	logger.SetLevel(DEBUG)
+	logger.Debug("shown")
+	if !strings.Contains(buf.String(), "shown") {
+		t.Error("Expected debug messages once the level is DEBUG")
+	}
+
+	buf.Reset()
+	logger.SetLevel(ERROR)
+	logger.Warn("hidden")
+	logger.Error("failed")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "failed") {
+		t.Errorf("Expected only errors, got %q", buf.String())
+	}
+
+	logger.ResetLevel()
+	if logger.Level() != INFO {
+		t.Errorf("Expected the mode default INFO after a reset, got %v", logger.Level())
+	}
+}
+
+func TestLog_Named(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLog(NewConsoleHandler(&buf, nil))
+	logger.SetLevel(WARNING)
+
+	limiter := logger.Named("ratelimit")
+	redis := limiter.Named("redis").With("db", 0)
+	if redis.Name() != "ratelimit.redis" {
+		t.Errorf("Expected a dotted name, got %q", redis.Name())
+	}
+
+	limiter.SetLevel(DEBUG)
+	logger.Info("root hidden")
+	redis.Debug("redis shown")
+	if strings.Contains(buf.String(), "root hidden") {
+		t.Error("Expected the root level to be unaffected by the child's")
+	}
+	if !strings.Contains(buf.String(), "[DEBUG] redis shown logger=ratelimit.redis db=0") {
+		t.Errorf("Expected the child to inherit its parent's level, got %q", buf.String())
+	}
+
+	limiter.ResetLevel()
+	if redis.Level() != WARNING {
+		t.Errorf("Expected the root level after a reset, got %v", redis.Level())
+	}
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	logger := NewLog(&recordingHandler{})
+	logger.SetLevel(INFO)
+	engine := New()
+	handler := LogLevelHandler(logger)
+	engine.GET("/log-level", handler)
+	engine.PUT("/log-level", handler)
+
+	do := func(method, body string) (int, logLevelState) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/log-level", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		engine.ServeHTTP(w, req)
+		var state logLevelState
+		json.Unmarshal(w.Body.Bytes(), &state)
+		return w.Code, state
+	}
+
+	code, state := do(http.MethodPut, `{"logger":"ratelimit","level":"debug"}`)
+	if code != http.StatusOK || state.Loggers["ratelimit"] != DEBUG {
+		t.Fatalf("Expected the level to be set, got %d %+v", code, state)
+	}
+	if logger.Named("ratelimit").Level() != DEBUG {
+		t.Error("Expected the named logger to use the new level")
+	}
+
+	code, state = do(http.MethodPut, `{"level":"warn"}`)
+	if code != http.StatusOK || state.Level != WARNING || logger.Level() != WARNING {
+		t.Errorf("Expected the root level to be set, got %d %+v", code, state)
+	}
+
+	code, state = do(http.MethodPut, `{"logger":"ratelimit"}`)
+	if _, ok := state.Loggers["ratelimit"]; code != http.StatusOK || ok {
+		t.Errorf("Expected the level to be reset, got %d %+v", code, state)
+	}
+
+	if code, _ = do(http.MethodPut, `{"level":"verbose"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an unknown level, got %d", http.StatusBadRequest, code)
+	}
+
+	if code, state = do(http.MethodGet, ""); code != http.StatusOK || state.Level != WARNING {
+		t.Errorf("Expected the levels, got %d %+v", code, state)
+	}
+
+	// A handler of a named logger takes names relative to it
+	logger.Named("cache").SetLevel(ERROR)
+	handler = LogLevelHandler(logger.Named("db"))
+	engine.PUT("/db/log-level", handler)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/db/log-level", strings.NewReader(`{"logger":"pool","level":"debug"}`)))
+	state = logLevelState{}
+	json.Unmarshal(w.Body.Bytes(), &state)
+	if w.Code != http.StatusOK || logger.Named("db").Named("pool").Level() != DEBUG || logger.Named("pool").Level() == DEBUG {
+		t.Errorf("Expected db.pool to be set, got %d %+v", w.Code, state)
+	}
+	if len(state.Loggers) != 1 || state.Loggers["pool"] != DEBUG {
+		t.Errorf("Expected only the children of db, got %+v", state.Loggers)
+	}
+}
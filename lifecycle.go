@@ -0,0 +1,218 @@
+package zen
+
+// This file contains the server lifecycle. The Engine owns the *http.Server it
+// serves with, so Shutdown can drain it, and Run ties serving to a context and
+// to SIGINT/SIGTERM. OnStart hooks run before the server accepts requests and
+// OnShutdown hooks after it has drained, which is where databases, queues and
+// log files should be closed.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long Run waits for in-flight requests to finish
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Hook is a function run when the server starts or shuts down
+type Hook func(ctx context.Context) error
+
+// OnStart registers hooks that run, in order, before the server starts
+// accepting requests. If a hook fails the server is not started and the
+// OnShutdown hooks don't run.
+//
+// Usage:
+//
+//	app.OnStart(func(ctx context.Context) error {
+//	    return db.PingContext(ctx)
+//	})
+func (engine *Engine) OnStart(hooks ...Hook) {
+	engine.onStart = append(engine.onStart, hooks...)
+}
+
+// OnShutdown registers hooks that run after the server has drained, in reverse
+// order of registration. Their context expires after the shutdown timeout.
+//
+// Usage:
+//
+//	app.OnShutdown(func(ctx context.Context) error {
+//	    return db.Close()
+//	})
+func (engine *Engine) OnShutdown(hooks ...Hook) {
+	engine.onShutdown = append(engine.onShutdown, hooks...)
+}
+
+// SetShutdownTimeout sets how long Run waits for in-flight requests to finish
+// before closing their connections. Default 10 seconds. It is a shortcut for
+// ServerConfig.ShutdownTimeout.
+func (engine *Engine) SetShutdownTimeout(timeout time.Duration) {
+	engine.serverConfig.ShutdownTimeout = timeout
+}
+
+// Run serves HTTP on addr until ctx is cancelled or the process receives
+// SIGINT or SIGTERM, then shuts down gracefully: in-flight requests are given
+// the shutdown timeout to finish and the OnShutdown hooks are run. A second
+// signal while draining terminates the process immediately. With
+// ServerConfig.GracefulRestart, SIGUSR2 restarts the binary without
+// dropping connections; the new process inherits the socket instead of binding addr.
+// Returns nil after a graceful shutdown.
+//
+// Usage:
+//
+//	if err := app.Run(context.Background(), ":8080"); err != nil {
+//	    log.Fatal(err)
+//	}
+func (e *Engine) Run(ctx context.Context, addr string) error {
+	// After a graceful restart the socket is inherited rather than bound
+	listener, err := inheritedListener()
+	if err == nil && listener == nil {
+		listener, err = e.listen(addr)
+	}
+	if err != nil {
+		return err
+	}
+	return e.RunListener(ctx, listener)
+}
+
+// RunListener is like Run but serves on an already open listener.
+func (e *Engine) RunListener(ctx context.Context, listener net.Listener) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server, done := e.newServer(listener.Addr().String())
+	if err := e.start(ctx, server.Addr); err != nil {
+		e.abortStart(listener, done)
+		return err
+	}
+
+	restart := make(chan os.Signal, 1)
+	if e.serverConfig.GracefulRestart && len(restartSignals) > 0 {
+		signal.Notify(restart, restartSignals...)
+		defer signal.Stop(restart)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	notifyReady()
+
+wait:
+	for {
+		select {
+		case err := <-serveErr:
+			if errors.Is(err, http.ErrServerClosed) {
+				// Shutdown was called elsewhere, wait for it to finish draining
+				<-done
+				return nil
+			}
+			e.Shutdown(e.serverConfig.ShutdownTimeout)
+			return err
+		case sig := <-restart:
+			e.logger.Infof("Received %v, restarting", sig)
+			if err := e.restart(listener); err != nil {
+				e.logger.Errorf("graceful restart failed, still serving: %v", err)
+				continue
+			}
+			break wait
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	// Restore the default signal behaviour so a second signal terminates
+	stop()
+	e.logger.Info("Shutting down server, waiting for in-flight requests")
+	return e.Shutdown(e.serverConfig.ShutdownTimeout)
+}
+
+// newServer creates the server owned by the engine. The returned channel is
+// closed when the server has been shut down.
+func (e *Engine) newServer(addr string) (*http.Server, chan struct{}) {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: e,
+	}
+	e.applyServerConfig(server)
+	done := make(chan struct{})
+
+	e.serverMu.Lock()
+	e.server = server
+	e.serverDone = done
+	e.addr = addr
+	e.serverMu.Unlock()
+	return server, done
+}
+
+// abortStart forgets a server whose OnStart hooks failed. It never started, so
+// there is nothing to shut down and a later Shutdown doesn't run the OnShutdown hooks.
+func (e *Engine) abortStart(listener net.Listener, done chan struct{}) {
+	listener.Close()
+	e.serverMu.Lock()
+	e.server, e.serverDone = nil, nil
+	e.serverMu.Unlock()
+	close(done)
+}
+
+// start runs the OnStart hooks and prints the routes in DevMode
+func (e *Engine) start(ctx context.Context, addr string) error {
+	for _, hook := range e.onStart {
+		if err := hook(ctx); err != nil {
+			e.logger.Errorf("start hook failed: %v", err)
+			return err
+		}
+	}
+
+	if e.IsDevMode() {
+		e.printRoutes()
+		fmt.Print(e.zenAsciiArt(addr))
+	}
+	return nil
+}
+
+// logSink is a log writer an Engine flushes and closes when it shuts down
+type logSink interface {
+	flush(ctx context.Context) error
+	Close() error
+}
+
+// addLogSink registers a log writer of the engine's middleware
+func (e *Engine) addLogSink(sink logSink) {
+	e.sinksMu.Lock()
+	defer e.sinksMu.Unlock()
+	e.sinks = append(e.sinks, sink)
+}
+
+// runShutdownHooks runs the OnShutdown hooks in reverse order, then flushes and
+// closes the log writers of the engine's middleware. Writers the engine doesn't
+// own, e.g. those of other engines, are left alone.
+func (e *Engine) runShutdownHooks(ctx context.Context) error {
+	var errs []error
+	for i := len(e.onShutdown) - 1; i >= 0; i-- {
+		if err := e.onShutdown[i](ctx); err != nil {
+			e.logger.Errorf("shutdown hook failed: %v", err)
+			errs = append(errs, err)
+		}
+	}
+
+	e.sinksMu.Lock()
+	sinks := e.sinks
+	e.sinks = nil
+	e.sinksMu.Unlock()
+	for _, sink := range sinks {
+		if err := sink.flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
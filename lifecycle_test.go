@@ -0,0 +1,158 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// runEngine starts engine.Run on a free port and returns its address and the result of Run
+func runEngine(t *testing.T, engine *Engine, ctx context.Context) (string, <-chan error) {
+	t.Helper()
+
+	started := make(chan string, 1)
+	engine.OnStart(func(context.Context) error {
+		started <- engine.addr
+		return nil
+	})
+
+	result := make(chan error, 1)
+	go func() {
+		result <- engine.Run(ctx, "127.0.0.1:0")
+	}()
+
+	select {
+	case addr := <-started:
+		return addr, result
+	case err := <-result:
+		t.Fatalf("Run failed: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the server to start")
+	}
+	return "", nil
+}
+
+func TestEngine_RunGracefulShutdown(t *testing.T) {
+	engine := New()
+	inFlight := make(chan struct{})
+	engine.GET("/slow", func(c *Context) {
+		close(inFlight)
+		time.Sleep(200 * time.Millisecond)
+		c.Text(http.StatusOK, "done")
+	})
+
+	var order []string
+	engine.OnShutdown(
+		func(context.Context) error { order = append(order, "database"); return nil },
+		func(context.Context) error { order = append(order, "queue"); return nil },
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, result := runEngine(t, engine, ctx)
+
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+
+	<-inFlight
+	cancel()
+
+	if got := <-response; got != "done" {
+		t.Errorf("Expected the in-flight request to complete, got %q", got)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("Expected Run to return nil after a graceful shutdown, got %v", err)
+	}
+	if !reflect.DeepEqual(order, []string{"queue", "database"}) {
+		t.Errorf("Expected shutdown hooks in reverse order, got %v", order)
+	}
+	if _, err := http.Get("http://" + addr + "/slow"); err == nil {
+		t.Error("Expected the server to stop accepting connections")
+	}
+}
+
+func TestEngine_ShutdownTimeout(t *testing.T) {
+	engine := New()
+	engine.SetShutdownTimeout(50 * time.Millisecond)
+	inFlight := make(chan struct{})
+	engine.GET("/stuck", func(c *Context) {
+		close(inFlight)
+		time.Sleep(time.Second)
+	})
+
+	hookRan := false
+	engine.OnShutdown(func(context.Context) error {
+		hookRan = true
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, result := runEngine(t, engine, ctx)
+	go http.Get("http://" + addr + "/stuck")
+
+	<-inFlight
+	start := time.Now()
+	cancel()
+
+	err := <-result
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Shutdown should give up after the timeout, took %v", elapsed)
+	}
+	if !hookRan {
+		t.Error("Shutdown hooks should run even if draining timed out")
+	}
+}
+
+func TestEngine_OnStartFailure(t *testing.T) {
+	engine := New()
+	failure := errors.New("database unreachable")
+	engine.OnStart(func(context.Context) error { return failure })
+	shutdownRan := false
+	engine.OnShutdown(func(context.Context) error {
+		shutdownRan = true
+		return nil
+	})
+
+	if err := engine.Run(context.Background(), "127.0.0.1:0"); !errors.Is(err, failure) {
+		t.Errorf("Expected %v, got %v", failure, err)
+	}
+	if shutdownRan {
+		t.Error("Expected no shutdown hooks to run when the server never started")
+	}
+}
+
+func TestEngine_ServeOnStartFailure(t *testing.T) {
+	engine := New()
+	failure := errors.New("database unreachable")
+	engine.OnStart(func(context.Context) error { return failure })
+	shutdownRan := false
+	engine.OnShutdown(func(context.Context) error {
+		shutdownRan = true
+		return nil
+	})
+
+	if err := engine.Serve("127.0.0.1:0"); !errors.Is(err, failure) {
+		t.Errorf("Expected %v from Serve, got %v", failure, err)
+	}
+	if err := engine.ServeTLS("127.0.0.1:0", "", ""); !errors.Is(err, failure) {
+		t.Errorf("Expected %v from ServeTLS, got %v", failure, err)
+	}
+	if err := engine.Shutdown(time.Second); err != nil || shutdownRan {
+		t.Errorf("Expected Shutdown to do nothing for a server that never started, got %v, hooks ran %v", err, shutdownRan)
+	}
+}
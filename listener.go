@@ -0,0 +1,200 @@
+package zen
+
+// This file contains the listeners the Engine can serve on besides TCP
+// addresses: Unix domain sockets, for running behind a local proxy, and
+// sockets passed in by systemd socket activation. Any of them can be served
+// with ServeListener or RunListener.
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// systemd passes activated sockets starting at this file descriptor
+const listenFDsStart = 3
+
+var ErrNoSystemdListeners = errors.New("no sockets passed by systemd")
+
+// ServeUnix serves HTTP on a Unix domain socket at path with the given file
+// mode. A stale socket left by a crashed process is replaced, and the socket
+// file is removed when the server shuts down.
+//
+// Usage:
+//
+//	app.ServeUnix("/run/myapp/http.sock", 0660)
+func (e *Engine) ServeUnix(path string, mode os.FileMode) error {
+	listener, err := ListenUnix(path, mode)
+	if err != nil {
+		return err
+	}
+	return e.ServeListener(listener)
+}
+
+// ListenUnix opens a Unix domain socket at path with the given file mode, for
+// use with ServeListener or RunListener. The socket file is removed when the
+// listener is closed. It fails if another process is serving on path.
+func ListenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use", path)
+		}
+		// Nobody is listening, the socket was left behind
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	listener.SetUnlinkOnClose(true)
+
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// ServeSystemd serves HTTP on the sockets passed by systemd socket activation.
+// With several sockets, requests from all of them are served.
+//
+// Usage, with a myapp.socket unit next to myapp.service:
+//
+//	if err := app.ServeSystemd(); err != nil {
+//	    log.Fatal(err)
+//	}
+func (e *Engine) ServeSystemd() error {
+	listeners, err := SystemdListeners()
+	if err != nil {
+		return err
+	}
+	if len(listeners) == 0 {
+		return ErrNoSystemdListeners
+	}
+	return e.ServeListener(MergeListeners(listeners...))
+}
+
+// SystemdListeners returns the sockets passed by systemd socket activation
+// (LISTEN_PID and LISTEN_FDS), or none if the process was not socket activated.
+// The variables are unset so child processes don't take the sockets for theirs.
+func SystemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, count)
+	for i := 0; i < count; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(listenFDsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		// FileListener duplicates the descriptor, so the original is closed
+		file := os.NewFile(uintptr(listenFDsStart+i), name)
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("systemd socket %s: %v", name, err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// MergeListeners returns a listener accepting connections from all the given
+// listeners, so one server can serve several sockets. Its address is the
+// address of the first listener.
+func MergeListeners(listeners ...net.Listener) net.Listener {
+	if len(listeners) == 1 {
+		return listeners[0]
+	}
+
+	m := &mergedListener{
+		listeners: listeners,
+		conns:     make(chan net.Conn),
+		errs:      make(chan error, len(listeners)),
+		closed:    make(chan struct{}),
+	}
+	for _, l := range listeners {
+		go m.accept(l)
+	}
+	return m
+}
+
+// mergedListener fans in the connections of several listeners
+type mergedListener struct {
+	listeners []net.Listener
+	conns     chan net.Conn
+	errs      chan error
+	closed    chan struct{}
+	once      sync.Once
+}
+
+func (m *mergedListener) accept(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case m.errs <- err:
+			case <-m.closed:
+			}
+			return
+		}
+		select {
+		case m.conns <- conn:
+		case <-m.closed:
+			conn.Close()
+			return
+		}
+	}
+}
+
+func (m *mergedListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-m.conns:
+		return conn, nil
+	case err := <-m.errs:
+		return nil, err
+	case <-m.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (m *mergedListener) Close() error {
+	var errs []error
+	m.once.Do(func() {
+		close(m.closed)
+		for _, l := range m.listeners {
+			errs = append(errs, l.Close())
+		}
+	})
+	return errors.Join(errs...)
+}
+
+func (m *mergedListener) Addr() net.Addr {
+	return m.listeners[0].Addr()
+}
@@ -0,0 +1,166 @@
+package zen
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEngine_ServeUnix(t *testing.T) {
+	engine := New()
+	engine.GET("/", func(c *Context) {
+		c.Text(http.StatusOK, "over unix")
+	})
+
+	path := filepath.Join(t.TempDir(), "http.sock")
+
+	// A socket left behind by a crashed process is replaced
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- engine.ServeUnix(path, 0660)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+
+	var resp *http.Response
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if resp, err = client.Get("http://unix/"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "over unix" {
+		t.Errorf("Expected body %q, got %q", "over unix", body)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0660 {
+		t.Errorf("Expected socket mode %v, got %v", os.FileMode(0660), info.Mode().Perm())
+	}
+
+	if _, err := ListenUnix(path, 0660); err == nil {
+		t.Error("Expected an error for a socket in use")
+	}
+
+	if err := engine.Shutdown(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	<-result
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the socket file to be removed on shutdown")
+	}
+}
+
+func TestSystemdListeners(t *testing.T) {
+	if os.Getenv("ZEN_SYSTEMD_CHILD") == "1" {
+		// Running as the socket activated child, see below
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		listeners, err := SystemdListeners()
+		if err != nil || len(listeners) != 1 {
+			t.Fatalf("Expected one listener, got %d: %v", len(listeners), err)
+		}
+		if os.Getenv("LISTEN_FDS") != "" {
+			t.Error("Expected LISTEN_FDS to be unset")
+		}
+		conn, err := listeners[0].Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte("activated"))
+		conn.Close()
+		return
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	file, err := listener.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSystemdListeners$")
+	cmd.Env = append(os.Environ(), "ZEN_SYSTEMD_CHILD=1", "LISTEN_FDS=1", "LISTEN_FDNAMES=http")
+	cmd.ExtraFiles = []*os.File{file} // becomes fd 3
+	output := make(chan []byte, 1)
+	go func() {
+		out, _ := cmd.CombinedOutput()
+		output <- out
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got, _ := io.ReadAll(conn)
+	conn.Close()
+
+	out := <-output
+	if string(got) != "activated" {
+		t.Errorf("Expected the child to serve on the passed socket, got %q\n%s", got, out)
+	}
+	if cmd.ProcessState == nil || !cmd.ProcessState.Success() {
+		t.Errorf("Child failed:\n%s", out)
+	}
+
+	if listeners, err := SystemdListeners(); err != nil || listeners != nil {
+		t.Errorf("Expected no listeners without socket activation, got %v %v", listeners, err)
+	}
+}
+
+func TestMergeListeners(t *testing.T) {
+	a, _ := net.Listen("tcp", "127.0.0.1:0")
+	b, _ := net.Listen("tcp", "127.0.0.1:0")
+	merged := MergeListeners(a, b)
+	defer merged.Close()
+
+	for _, l := range []net.Listener{a, b} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		accepted, err := merged.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accepted.LocalAddr().String() != l.Addr().String() {
+			t.Errorf("Expected a connection on %v, got %v", l.Addr(), accepted.LocalAddr())
+		}
+		accepted.Close()
+		conn.Close()
+	}
+
+	merged.Close()
+	if _, err := merged.Accept(); err == nil {
+		t.Error("Expected Accept to fail after Close")
+	}
+}
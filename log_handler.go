@@ -0,0 +1,322 @@
+package zen
+
+// This file contains the slog handlers Log writes to: a text handler that is
+// coloured on a terminal, and JSON and logfmt handlers for log aggregators.
+// They name the levels like Log does (DEBUG, INFO, SUCCESS, WARNING, ERROR and
+// FATAL), so the custom SUCCESS and FATAL levels don't show up as "INFO+2".
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// LogFormatEnv is the environment variable NewLogger reads the log format from
+const LogFormatEnv = "ZEN_LOG_FORMAT"
+
+// Log formats accepted by NewHandler
+const (
+	LogFormatText   = "text"
+	LogFormatJSON   = "json"
+	LogFormatLogfmt = "logfmt"
+)
+
+// NewHandler returns a handler writing to w in format: "text" (or empty), which is
+// coloured when w is a terminal, "json" or "logfmt". The level defaults to DEBUG,
+// debug messages are still only written in DevMode.
+func NewHandler(format string, w io.Writer, opts *slog.HandlerOptions) (slog.Handler, error) {
+	switch strings.ToLower(format) {
+	case "", LogFormatText, "console":
+		return NewConsoleHandler(w, opts), nil
+	case LogFormatJSON:
+		return NewJSONHandler(w, opts), nil
+	case LogFormatLogfmt:
+		return NewLogfmtHandler(w, opts), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// NewJSONHandler returns a handler writing one JSON object per line, e.g.
+// {"time":"...","level":"INFO","msg":"user signed in","user":"42"}
+func NewJSONHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	return slog.NewJSONHandler(w, handlerOptions(opts))
+}
+
+// NewLogfmtHandler returns a handler writing logfmt lines, e.g.
+// time=... level=INFO msg="user signed in" user=42
+func NewLogfmtHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	return slog.NewTextHandler(w, handlerOptions(opts))
+}
+
+// handlerOptions defaults the level to DEBUG and names the levels like Log does
+func handlerOptions(opts *slog.HandlerOptions) *slog.HandlerOptions {
+	o := slog.HandlerOptions{Level: slog.LevelDebug}
+	if opts != nil {
+		o = *opts
+		if o.Level == nil {
+			o.Level = slog.LevelDebug
+		}
+	}
+	replace := o.ReplaceAttr
+	o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.LevelKey {
+			if level, ok := a.Value.Any().(slog.Level); ok {
+				a.Value = slog.StringValue(levelName(level))
+			}
+		}
+		if replace != nil {
+			return replace(groups, a)
+		}
+		return a
+	}
+	return &o
+}
+
+// levelName returns the name of a slog level
+func levelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "DEBUG"
+	case level < LevelSuccess:
+		return "INFO"
+	case level < slog.LevelWarn:
+		return "SUCCESS"
+	case level < slog.LevelError:
+		return "WARNING"
+	case level < LevelFatal:
+		return "ERROR"
+	}
+	return "FATAL"
+}
+
+// levelColor returns the colour of a slog level
+func levelColor(level slog.Level) string {
+	switch levelName(level) {
+	case "DEBUG":
+		return Gray
+	case "INFO":
+		return Blue
+	case "SUCCESS":
+		return Green
+	case "WARNING":
+		return Yellow
+	case "ERROR":
+		return Red
+	}
+	return Purple
+}
+
+// ConsoleHandler writes human readable lines, e.g.
+// 2024/01/02 15:04:05 [INFO] user signed in user=42
+// coloured by level when writing to a terminal.
+type ConsoleHandler struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	opts   slog.HandlerOptions
+	color  bool
+	groups []string
+	attrs  string // fields added with WithAttrs, already formatted
+}
+
+// NewConsoleHandler returns a handler writing text to w. Colours are used when
+// w is a terminal and the NO_COLOR environment variable is not set.
+func NewConsoleHandler(w io.Writer, opts *slog.HandlerOptions) *ConsoleHandler {
+	return &ConsoleHandler{
+		w:     w,
+		mu:    &sync.Mutex{},
+		opts:  *handlerOptions(opts),
+		color: isTerminal(w) && os.Getenv("NO_COLOR") == "",
+	}
+}
+
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// WithColor returns a handler that uses colours or not, regardless of the output
+func (h *ConsoleHandler) WithColor(on bool) *ConsoleHandler {
+	clone := *h
+	clone.color = on
+	return &clone
+}
+
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+func (h *ConsoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	color := ""
+	if h.color {
+		color = levelColor(r.Level)
+		b.WriteString(color)
+	}
+	b.WriteString(r.Time.Format("2006/01/02 15:04:05"))
+	b.WriteString(" [")
+	b.WriteString(levelName(r.Level))
+	b.WriteString("] ")
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		h.appendAttr(&b, nil, slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", frame.File, frame.Line)))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(&b, h.groups, a)
+		return true
+	})
+	if color != "" {
+		b.WriteString(Reset)
+	}
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		h.appendAttr(&b, h.groups, a)
+	}
+	clone := *h
+	clone.attrs += b.String()
+	return &clone
+}
+
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(append([]string(nil), h.groups...), name)
+	return &clone
+}
+
+// appendAttr writes " key=value", with the keys of groups joined by dots
+func (h *ConsoleHandler) appendAttr(b *strings.Builder, groups []string, a slog.Attr) {
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(groups, a)
+	}
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			groups = append(append([]string(nil), groups...), a.Key)
+		}
+		for _, member := range a.Value.Group() {
+			h.appendAttr(b, groups, member)
+		}
+		return
+	}
+
+	b.WriteByte(' ')
+	for _, group := range groups {
+		b.WriteString(group)
+		b.WriteByte('.')
+	}
+	b.WriteString(a.Key)
+	b.WriteByte('=')
+	b.WriteString(formatValue(a.Value))
+}
+
+// formatValue formats a field value, quoting strings logfmt style where needed
+func formatValue(v slog.Value) string {
+	var s string
+	switch v.Kind() {
+	case slog.KindString:
+		s = v.String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			s = err.Error()
+		} else {
+			s = v.String()
+		}
+	default:
+		return v.String()
+	}
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r)
+	}) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// multiHandler passes records to several handlers
+type multiHandler []slog.Handler
+
+// NewMultiHandler returns a handler passing records to each of handlers, e.g. to
+// write coloured text to the console and JSON to a log file
+func NewMultiHandler(handlers ...slog.Handler) slog.Handler {
+	return multiHandler(handlers)
+}
+
+func (h multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := make(multiHandler, len(h))
+	for i, handler := range h {
+		clone[i] = handler.WithAttrs(attrs)
+	}
+	return clone
+}
+
+func (h multiHandler) WithGroup(name string) slog.Handler {
+	clone := make(multiHandler, len(h))
+	for i, handler := range h {
+		clone[i] = handler.WithGroup(name)
+	}
+	return clone
+}
+
+// argsToAttrs converts alternating keys and values, or slog.Attrs, to attributes
+func argsToAttrs(args []any) []slog.Attr {
+	var record slog.Record
+	record.Add(args...)
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
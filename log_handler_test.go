@@ -0,0 +1,132 @@
+package zen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLog_JSONHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLog(NewJSONHandler(&buf, nil))
+
+	logger.Success("user signed in", "user", "42", "attempts", 2)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "SUCCESS" || entry["msg"] != "user signed in" {
+		t.Errorf("Expected level SUCCESS and the message, got %v", entry)
+	}
+	if entry["user"] != "42" || entry["attempts"] != float64(2) {
+		t.Errorf("Expected the fields, got %v", entry)
+	}
+	if strings.Contains(buf.String(), "\033[") {
+		t.Error("Expected no colour codes in JSON")
+	}
+}
+
+func TestLog_LogfmtHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLog(NewLogfmtHandler(&buf, nil)).With("component", "jobs")
+
+	logger.Warn("job failed", "error", errors.New("disk full"))
+
+	out := buf.String()
+	for _, want := range []string{"level=WARNING", `msg="job failed"`, "component=jobs", `error="disk full"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %s in %q", want, out)
+		}
+	}
+}
+
+func TestLog_ConsoleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLog(NewConsoleHandler(&buf, nil)).With("request", "abc").WithGroup("db")
+
+	logger.Info("query", "table", "users", "sql", "SELECT 1", slog.Group("pool", "open", 3))
+
+	out := buf.String()
+	if !strings.Contains(out, `[INFO] query request=abc db.table=users db.sql="SELECT 1" db.pool.open=3`) {
+		t.Errorf("Unexpected console line %q", out)
+	}
+	if strings.Contains(out, "\033[") {
+		t.Error("Expected no colours when not writing to a terminal")
+	}
+}
+
+// recordingHandler keeps the records it handles
+type recordingHandler struct {
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler            { return h }
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.records = append(h.records, r)
+	return nil
+}
+
+func TestSetLogHandler(t *testing.T) {
+	previous := defaultLogger()
+	defer defaultLog.Store(previous)
+
+	handler := &recordingHandler{}
+	SetLogHandler(handler)
+	Error("payment failed", "order", 7)
+	Infof("retrying in %ds", 5)
+
+	if len(handler.records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(handler.records))
+	}
+	record := handler.records[0]
+	if record.Level != slog.LevelError || record.Message != "payment failed" || record.NumAttrs() != 1 {
+		t.Errorf("Unexpected record %v %q with %d fields", record.Level, record.Message, record.NumAttrs())
+	}
+	if handler.records[1].Message != "retrying in 5s" {
+		t.Errorf("Expected the formatted message, got %q", handler.records[1].Message)
+	}
+}
+
+func TestSetLogHandler_WhileLogging(t *testing.T) {
+	previous := defaultLogger()
+	defer defaultLog.Store(previous)
+	SetLogHandler(slog.DiscardHandler)
+
+	// Run with -race: replacing the handler must not race with logging
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			SetLogHandler(slog.DiscardHandler)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			Info("tick", "i", i)
+			Named("worker").Warn("tick")
+			SetLogLevel(INFO)
+		}
+	}()
+	wg.Wait()
+}
+
+func TestNewLogger_Format(t *testing.T) {
+	t.Setenv(LogFormatEnv, "json")
+	if _, ok := NewLogger().Handler().(*slog.JSONHandler); !ok {
+		t.Errorf("Expected a JSON handler, got %T", NewLogger().Handler())
+	}
+
+	if _, err := NewHandler("xml", &bytes.Buffer{}, nil); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -1,13 +1,21 @@
 package zen
 
+// This file contains the Log type and the request Logger middleware. Log is
+// backed by a slog.Handler: messages carry key/value fields, e.g.
+// zen.Info("user signed in", "user", id), and are written as coloured text on
+// a terminal, or as JSON or logfmt for log aggregators. Any slog.Handler can
+// be plugged in with SetLogHandler.
+
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
-	"os/signal"
-	"path/filepath"
+	"runtime"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,161 +33,286 @@ const (
 	FATAL                   // FATAL represents critical errors causing the program to terminate.
 )
 
-// Log is a wrapper around the standard log.Logger that is used to output log messages.
-// It provides additional functionality (e.g., log levels) in the context of application logging.
+// The slog levels of SUCCESS and FATAL, which slog doesn't define
+const (
+	LevelSuccess = slog.Level(2)
+	LevelFatal   = slog.Level(12)
+)
+
+// Level returns the slog level of the log level
+func (l LogLevel) Level() slog.Level {
+	switch l {
+	case DEBUG:
+		return slog.LevelDebug
+	case SUCCESS:
+		return LevelSuccess
+	case WARNING:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	case FATAL:
+		return LevelFatal
+	}
+	return slog.LevelInfo
+}
+
+// String returns the name of the log level, e.g. "WARNING"
+func (l LogLevel) String() string {
+	return levelName(l.Level())
+}
+
+// Log is the logger of the zen framework. It writes to a slog.Handler and knows
+// the mode of its engine: debug messages are only written in DevMode.
 type Log struct {
-	logger *log.Logger // The internal logger that handles actual log message writing.
+	handler slog.Handler // The handler log records are written to.
+	mode    *engineMode  // The mode of the engine the logger belongs to, nil for the default mode.
+	exit    func(int)    // Called by Fatal, os.Exit unless replaced in tests.
+	name    string       // The name given with Named, empty for a root logger.
+	unnamed slog.Handler // The handler without the "logger" field Named adds, nil for a root logger.
+	levels  *logLevels   // The levels set at runtime, shared with the loggers derived from it.
 }
 
-// defaultLogger is a global instance of Log that writes log messages to the standard output (os.Stdout).
-// It uses a default logger initialized with no prefix and no flags.
-var defaultLogger = &Log{
-	logger: log.New(os.Stdout, "", 0), // log.New creates a new logger that writes to os.Stdout with no prefix or flags.
+// defaultLog holds the logger used by the package-level functions. It writes
+// to standard output in the format set by ZEN_LOG_FORMAT, coloured text by
+// default, and can be replaced with SetLogHandler while requests are logging.
+var defaultLog = func() *atomic.Pointer[Log] {
+	var l atomic.Pointer[Log]
+	l.Store(NewLogger())
+	return &l
+}()
+
+// defaultLogger returns the logger used by the package-level functions
+func defaultLogger() *Log {
+	return defaultLog.Load()
 }
 
-// NewLogger creates and returns a new instance of Log. The logger is initialized to write to standard output.
+// NewLogger creates a logger writing to standard output in the format set by the
+// ZEN_LOG_FORMAT environment variable: "json", "logfmt" or "text" (the default,
+// coloured on a terminal).
 func NewLogger() *Log {
-	return &Log{
-		logger: log.New(os.Stdout, "", 0), // Initializes the logger to write to os.Stdout with no prefix or flags.
+	handler, err := NewHandler(os.Getenv(LogFormatEnv), os.Stdout, nil)
+	if err != nil {
+		handler = NewConsoleHandler(os.Stdout, nil)
+	}
+	return NewLog(handler)
+}
+
+// NewLog creates a logger writing to handler, which can be any slog.Handler.
+//
+// Usage:
+//
+//	logger := zen.NewLog(zen.NewJSONHandler(os.Stderr, nil))
+//	logger.Info("cache warmed", "entries", n)
+func NewLog(handler slog.Handler) *Log {
+	return &Log{handler: handler, levels: newLogLevels()}
+}
+
+// SetLogHandler makes the package-level functions (zen.Info etc.) write to handler.
+// Levels set with SetLogLevel are kept. It is safe to call while serving.
+func SetLogHandler(handler slog.Handler) {
+	defaultLog.Store(&Log{handler: handler, levels: defaultLogger().levels})
+}
+
+// Logger returns the logger of the engine. It follows the engine's mode: debug
+// messages are only written in DevMode and colours are left out in Test mode.
+func (engine *Engine) Logger() *Log {
+	return engine.logger
+}
+
+// SetLogHandler makes the engine's logger write to handler. Levels set on the
+// logger are kept. Like the engine's other settings, call it before serving.
+//
+// Usage:
+//
+//	app.SetLogHandler(zen.NewJSONHandler(os.Stdout, nil))
+func (engine *Engine) SetLogHandler(handler slog.Handler) {
+	engine.logger = &Log{handler: handler, mode: &engine.mode, levels: engine.logger.levels}
+}
+
+// Handler returns the slog.Handler the logger writes to
+func (l *Log) Handler() slog.Handler {
+	return l.handler
+}
+
+// Slog returns a *slog.Logger writing to the same handler, for libraries that take one
+func (l *Log) Slog() *slog.Logger {
+	return slog.New(l.handler)
+}
+
+// With returns a logger that adds the given key/value fields to every message
+//
+// Usage:
+//
+//	jobs := zen.NewLogger().With("component", "jobs")
+//	jobs.Info("job finished", "id", job.ID)
+func (l *Log) With(args ...any) *Log {
+	attrs := argsToAttrs(args)
+	return l.derive(func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs) })
+}
+
+// WithGroup returns a logger that nests the fields of every message under name
+func (l *Log) WithGroup(name string) *Log {
+	return l.derive(func(h slog.Handler) slog.Handler { return h.WithGroup(name) })
+}
+
+// derive returns a logger sharing l's name, mode and levels, whose handlers are
+// l's passed through wrap
+func (l *Log) derive(wrap func(slog.Handler) slog.Handler) *Log {
+	child := &Log{handler: wrap(l.handler), mode: l.mode, exit: l.exit, name: l.name, levels: l.levels}
+	if l.unnamed != nil {
+		child.unnamed = wrap(l.unnamed)
 	}
+	return child
 }
 
-// TODO: also use file logger for the log and logf functions
+// currentMode returns the mode of the logger's engine, or the default mode
+func (l *Log) currentMode() Mode {
+	if l.mode == nil {
+		return GetMode()
+	}
+	return l.mode.get()
+}
 
 // Package-level functions using default logger
 
-// Debug logs a debug level message using the default logger.
-func Debug(v ...interface{}) { defaultLogger.Debug(v...) }
+// Debug logs a debug level message with key/value fields using the default logger.
+func Debug(msg string, args ...any) { defaultLogger().log(DEBUG, msg, args...) }
 
 // Debugf logs a formatted debug level message using the default logger.
-func Debugf(format string, v ...interface{}) { defaultLogger.Debugf(format, v...) }
+func Debugf(format string, v ...interface{}) { defaultLogger().logf(DEBUG, format, v...) }
 
-// Info logs an info level message using the default logger.
-func Info(v ...interface{}) { defaultLogger.Info(v...) }
+// Info logs an info level message with key/value fields using the default logger.
+//
+// Usage:
+//
+//	zen.Info("user signed in", "user", id, "method", "password")
+func Info(msg string, args ...any) { defaultLogger().log(INFO, msg, args...) }
 
 // Infof logs a formatted info level message using the default logger.
-func Infof(format string, v ...interface{}) { defaultLogger.Infof(format, v...) }
+func Infof(format string, v ...interface{}) { defaultLogger().logf(INFO, format, v...) }
 
-// Success logs a success level message using the default logger.
-func Success(v ...interface{}) { defaultLogger.Success(v...) }
+// Success logs a success level message with key/value fields using the default logger.
+func Success(msg string, args ...any) { defaultLogger().log(SUCCESS, msg, args...) }
 
-func Successf(format string, v ...interface{}) { defaultLogger.Successf(format, v...) }
+// Successf logs a formatted success level message using the default logger.
+func Successf(format string, v ...interface{}) { defaultLogger().logf(SUCCESS, format, v...) }
 
-// Warn logs a warning level message using the default logger.
-func Warn(v ...interface{}) { defaultLogger.Warn(v...) }
+// Warn logs a warning level message with key/value fields using the default logger.
+func Warn(msg string, args ...any) { defaultLogger().log(WARNING, msg, args...) }
 
 // Warnf logs a formatted warning level message using the default logger.
-func Warnf(format string, v ...interface{}) { defaultLogger.Warnf(format, v...) }
+func Warnf(format string, v ...interface{}) { defaultLogger().logf(WARNING, format, v...) }
 
-// Error logs an error level message using the default logger.
-func Error(v ...interface{}) { defaultLogger.Error(v...) }
+// Error logs an error level message with key/value fields using the default logger.
+func Error(msg string, args ...any) { defaultLogger().log(ERROR, msg, args...) }
 
 // Errorf logs a formatted error level message using the default logger.
-func Errorf(format string, v ...interface{}) { defaultLogger.Errorf(format, v...) }
+func Errorf(format string, v ...interface{}) { defaultLogger().logf(ERROR, format, v...) }
 
-// Fatal logs a fatal level message using the default logger and exits the application.
-func Fatal(v ...interface{}) { defaultLogger.Fatal(v...) }
+// Fatal logs a fatal level message with key/value fields using the default logger and exits the application.
+func Fatal(msg string, args ...any) {
+	defaultLogger().log(FATAL, msg, args...)
+	defaultLogger().exitProcess()
+}
 
 // Fatalf logs a formatted fatal level message using the default logger and exits the application.
-func Fatalf(format string, v ...interface{}) { defaultLogger.Fatalf(format, v...) }
+func Fatalf(format string, v ...interface{}) {
+	defaultLogger().logf(FATAL, format, v...)
+	defaultLogger().exitProcess()
+}
 
-func (l *Log) log(level LogLevel, color, prefix string, v ...interface{}) {
-	if !IsDevMode() && level == DEBUG {
-		return
+// enabled reports whether messages of level are written
+func (l *Log) enabled(level LogLevel) bool {
+	if level < l.Level() {
+		return false
 	}
+	return l.handler.Enabled(context.Background(), level.Level())
+}
 
-	timestamp := time.Now().Format("2006/01/02 15:04:05")
-	message := fmt.Sprint(v...)
-
-	l.logger.Printf("%s%s [%s] %s%s",
-		color,
-		timestamp,
-		prefix,
-		message,
-		Reset,
-	)
-
-	if IsFileLoggingEnabled(){
-		fileLogger.Printf("%s [%s] %s",
-			timestamp,
-			prefix,
-			message,
-		)
+func (l *Log) log(level LogLevel, msg string, args ...any) {
+	if !l.enabled(level) {
+		return
 	}
+	l.write(level, msg, args)
 }
 
-func (l *Log) logf(level LogLevel, color, prefix string, format string, v ...interface{}) {
-	if !IsDevMode() && level == DEBUG {
+func (l *Log) logf(level LogLevel, format string, v ...interface{}) {
+	if !l.enabled(level) {
 		return
 	}
+	l.write(level, fmt.Sprintf(format, v...), nil)
+}
 
-	timestamp := time.Now().Format("2006/01/02 15:04:05")
-	message := fmt.Sprintf(format, v...)
-
-	l.logger.Printf("%s%s [%s] %s%s",
-		color,
-		timestamp,
-		prefix,
-		message,
-		Reset,
-	)
-
-	if IsFileLoggingEnabled(){
-		fileLogger.Printf("%s [%s] %s",
-			timestamp,
-			prefix,
-			message,
-		)
+// write builds a record with the caller's source location and hands it to the handler
+func (l *Log) write(level LogLevel, msg string, args []any) {
+	// Skip write, log/logf and the exported method for the caller's source location
+	var pcs [1]uintptr
+	runtime.Callers(4, pcs[:])
+	record := slog.NewRecord(time.Now(), level.Level(), msg, pcs[0])
+	record.Add(args...)
+
+	handler := l.handler
+	if console, ok := handler.(*ConsoleHandler); ok && l.currentMode() == Test {
+		handler = console.WithColor(false)
 	}
+	handler.Handle(context.Background(), record)
 }
 
-func (l *Log) Debug(v ...interface{}) {
-	l.log(DEBUG, Gray, "DEBUG", v...)
+func (l *Log) Debug(msg string, args ...any) {
+	l.log(DEBUG, msg, args...)
 }
 
 func (l *Log) Debugf(format string, v ...interface{}) {
-	l.logf(DEBUG, Gray, "DEBUG", format, v...)
+	l.logf(DEBUG, format, v...)
 }
 
-func (l *Log) Info(v ...interface{}) {
-	l.log(INFO, Blue, "INFO", v...)
+func (l *Log) Info(msg string, args ...any) {
+	l.log(INFO, msg, args...)
 }
 
 func (l *Log) Infof(format string, v ...interface{}) {
-	l.logf(INFO, Blue, "INFO", format, v...)
+	l.logf(INFO, format, v...)
 }
 
-func (l *Log) Success(v ...interface{}) {
-	l.log(SUCCESS, Green, "SUCCESS", v...)
+func (l *Log) Success(msg string, args ...any) {
+	l.log(SUCCESS, msg, args...)
 }
 
 func (l *Log) Successf(format string, v ...interface{}) {
-	l.logf(SUCCESS, Green, "SUCCESS", format, v...)
+	l.logf(SUCCESS, format, v...)
 }
 
-func (l *Log) Warn(v ...interface{}) {
-	l.log(WARNING, Yellow, "WARNING", v...)
+func (l *Log) Warn(msg string, args ...any) {
+	l.log(WARNING, msg, args...)
 }
 
 func (l *Log) Warnf(format string, v ...interface{}) {
-	l.logf(WARNING, Yellow, "WARNING", format, v...)
+	l.logf(WARNING, format, v...)
 }
 
-func (l *Log) Error(v ...interface{}) {
-	l.log(ERROR, Red, "ERROR", v...)
+func (l *Log) Error(msg string, args ...any) {
+	l.log(ERROR, msg, args...)
 }
 
 func (l *Log) Errorf(format string, v ...interface{}) {
-	l.logf(ERROR, Red, "ERROR", format, v...)
+	l.logf(ERROR, format, v...)
 }
 
-func (l *Log) Fatal(v ...interface{}) {
-	l.log(FATAL, Purple, "FATAL", v...)
-	os.Exit(1)
+func (l *Log) Fatal(msg string, args ...any) {
+	l.log(FATAL, msg, args...)
+	l.exitProcess()
 }
 
 func (l *Log) Fatalf(format string, v ...interface{}) {
-	l.logf(FATAL, Purple, "FATAL", format, v...)
+	l.logf(FATAL, format, v...)
+	l.exitProcess()
+}
+
+func (l *Log) exitProcess() {
+	if l.exit != nil {
+		l.exit(1)
+		return
+	}
 	os.Exit(1)
 }
 
@@ -188,7 +321,12 @@ type LoggerConfig struct {
 	// skip logging for specific paths
 	SkipPaths []string
 
-	// Custom log format function
+	// Access log format: "" for the default coloured format, LogFormatCommon,
+	// LogFormatCombined, LogFormatJSON, or a template such as
+	// "${status} ${latency} ${method} ${path}"
+	Format string
+
+	// Custom log format function, used instead of Format when set
 	Formatter func(*Context, time.Duration) string
 
 	// Enable/diable file logging
@@ -196,13 +334,14 @@ type LoggerConfig struct {
 
 	// file path for logging to file
 	LogFilePath string
-}
 
-var (
-	fileLogger *log.Logger
-	logFile    *os.File
-	loggerMu   sync.Once
-)
+	// Rotation of the log file, never by default
+	Rotate RotateConfig
+
+	// Write the access log from a background goroutine through an AsyncWriter,
+	// nil to write it from the request goroutine
+	Async *AsyncConfig
+}
 
 // DefaultLoggerConfig returns a LoggerConfig with default settings
 func DefaultLoggerConfig() LoggerConfig {
@@ -212,21 +351,123 @@ func DefaultLoggerConfig() LoggerConfig {
 	}
 }
 
-// Logger middleware logs the incoming HTTP request details
+// Logger middleware logs the incoming HTTP request details. Its log writers
+// are flushed and closed when the engine serving it shuts down; use
+// NewAccessLogger to read their counters or close them yourself.
 func Logger(config ...LoggerConfig) HandlerFunc {
+	return NewAccessLogger(config...).Handler()
+}
+
+// AccessLogger is the Logger middleware together with the writers it owns
+type AccessLogger struct {
+	config  LoggerConfig
+	format  accessLogFunc
+	console *log.Logger
+	file    *log.Logger
+	logFile *RotatingFile  // the file of LoggerConfig.LogToFile
+	async   []*AsyncWriter // the writers of console and file when LoggerConfig.Async is set
+	engines sync.Map       // the engines it has served, which close it on shutdown
+}
+
+// NewAccessLogger opens the writers of config and returns the access logger.
+//
+// Usage:
+//
+//	access := zen.NewAccessLogger(zen.LoggerConfig{LogToFile: true, Async: &zen.AsyncConfig{Policy: zen.DropWhenFull}})
+//	app.Apply(access.Handler())
+//	app.GET("/metrics", func(c *zen.Context) {
+//	    c.Text(http.StatusOK, fmt.Sprintf("access_log_dropped %d\n", access.Dropped()))
+//	})
+func NewAccessLogger(config ...LoggerConfig) *AccessLogger {
 	cfg := DefaultLoggerConfig()
 	if len(config) > 0 {
 		cfg = config[0]
 	}
+	l := &AccessLogger{config: cfg, format: newAccessLogFunc(cfg)}
+
+	// Console output goes to the standard logger, or a copy of it writing
+	// through an AsyncWriter
+	l.console = log.Default()
+	if cfg.Async != nil {
+		out := NewAsyncWriter(log.Writer(), *cfg.Async)
+		l.async = append(l.async, out)
+		l.console = log.New(out, log.Prefix(), log.Flags())
+	}
 
-	// Initialize file logger if enabled
+	// Open the log file if enabled, each config writing to its own file
 	if cfg.LogToFile {
-		if err := initFileLogger(cfg); err != nil {
+		file, err := initFileLogger(cfg)
+		l.logFile = file
+		if err != nil {
 			Warnf("failed to initialise file logger: %v", err)
+		} else if cfg.Async != nil {
+			out := NewAsyncWriter(file, *cfg.Async)
+			l.async = append(l.async, out)
+			l.file = log.New(out, "", log.LstdFlags)
+		} else {
+			l.file = log.New(file, "", log.LstdFlags)
 		}
 	}
+	return l
+}
+
+// Written returns the number of lines written by the asynchronous writers
+func (l *AccessLogger) Written() uint64 {
+	var n uint64
+	for _, a := range l.async {
+		n += a.Written()
+	}
+	return n
+}
+
+// Dropped returns the number of lines the asynchronous writers dropped because
+// their buffer was full
+func (l *AccessLogger) Dropped() uint64 {
+	var n uint64
+	for _, a := range l.async {
+		n += a.Dropped()
+	}
+	return n
+}
+
+// Flush writes out the buffered lines, waiting at most until ctx is done
+func (l *AccessLogger) Flush(ctx context.Context) error {
+	var errs []error
+	for _, a := range l.async {
+		errs = append(errs, a.flush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the asynchronous writers, writing out their lines, and closes the
+// log file. Lines logged afterwards are written directly, reopening the file.
+func (l *AccessLogger) Close() error {
+	var errs []error
+	for _, a := range l.async {
+		errs = append(errs, a.Close())
+	}
+	if l.logFile != nil {
+		errs = append(errs, l.logFile.Close())
+	}
+	l.engines.Clear()
+	return errors.Join(errs...)
+}
+
+// flush implements logSink
+func (l *AccessLogger) flush(ctx context.Context) error {
+	return l.Flush(ctx)
+}
 
+// Handler returns the middleware
+func (l *AccessLogger) Handler() HandlerFunc {
+	cfg, format, console, fileLogger := l.config, l.format, l.console, l.file
 	return func(c *Context) {
+		if c.engine != nil {
+			if _, served := l.engines.LoadOrStore(c.engine, struct{}{}); !served {
+				c.engine.addLogSink(l)
+			}
+		}
+
 		// Start timer
 		start := time.Now()
 		path := c.GetURLPath()
@@ -242,8 +483,18 @@ func Logger(config ...LoggerConfig) HandlerFunc {
 
 		c.Next()
 
-		end := time.Now()
-		latency := end.Sub(start)
+		latency := time.Since(start)
+
+		// Custom and built-in formats are written as they are, without the time
+		// prefix of the standard logger
+		if format != nil {
+			line := format(c, start, latency) + "\n"
+			console.Writer().Write([]byte(line))
+			if fileLogger != nil {
+				fileLogger.Writer().Write([]byte(line))
+			}
+			return
+		}
 
 		if raw != "" {
 			path = path + "?" + raw
@@ -252,13 +503,17 @@ func Logger(config ...LoggerConfig) HandlerFunc {
 		// Get status code color
 		statusColor := ColorForStatus(c.Writer.Status())
 		methodColor := GetMethodColor(c.GetMethod())
+		reset, gray := Reset, Gray
+		if c.Mode() == Test {
+			statusColor, methodColor, reset, gray = "", "", "", ""
+		}
 
 		consoleLog := fmt.Sprintf("%s %3d %s| %13v | %15s | %-7s %s %s\n",
-			statusColor, c.Writer.Status(), Reset,
+			statusColor, c.Writer.Status(), reset,
 			latency,
 			c.GetClientIP(),
-			methodColor+c.Request.Method+Reset,
-			Gray, path,
+			methodColor+c.Request.Method+reset,
+			gray, path,
 		)
 
 		fileLog := fmt.Sprintf("%d | %13v | %15s | %-7s %s\n",
@@ -270,82 +525,46 @@ func Logger(config ...LoggerConfig) HandlerFunc {
 		)
 
 		// Console output
-		log.Print(consoleLog)
+		console.Print(consoleLog)
 
 		// File output
-		if cfg.LogToFile && fileLogger != nil {
+		if fileLogger != nil {
 			fileLogger.Print(fileLog)
 		}
 	}
 }
 
-// initFileLogger initializes the file logger with proper error handling
-func initFileLogger(cfg LoggerConfig) error {
+// initFileLogger opens the log file of cfg
+func initFileLogger(cfg LoggerConfig) (*RotatingFile, error) {
 	if cfg.LogFilePath == "" {
 		cfg.LogFilePath = "logs/zen.log" // Fallback if path is empty
 	}
 
-	// Ensure path is absolute
-	if !filepath.IsAbs(cfg.LogFilePath) {
-		// Get current working directory
-		cwd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get working directory: %v", err)
-		}
-		cfg.LogFilePath = filepath.Join(cwd, cfg.LogFilePath)
+	file, err := OpenLogFile(cfg.LogFilePath, cfg.Rotate)
+	if err != nil {
+		return nil, err
 	}
-
-	var initErr error
-	loggerMu.Do(func() {
-		// Create directory structure
-		dir := filepath.Dir(cfg.LogFilePath)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			initErr = fmt.Errorf("failed to create log directory: %v", err)
-			return
-		}
-
-		// Open log file
-		file, err := os.OpenFile(cfg.LogFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			initErr = fmt.Errorf("failed to open log file: %v", err)
-			return
-		}
-
-		logFile = file
-		fileLogger = log.New(file, "", log.LstdFlags)
-		Infof("Initialized file logger at: %s", cfg.LogFilePath)
-		setupCleanup()
-	})
-
-	return initErr
+	Infof("Initialized file logger at: %s", file.Path())
+	return file, nil
 }
 
+// IsFileLoggingEnabled reports whether a log file is open
 func IsFileLoggingEnabled() bool {
-	return defaultLogger != nil && fileLogger != nil
-}
-
-// Close gracefully closes the log file if it exists
-func Close() error {
-	if logFile != nil {
-		if err := logFile.Close(); err != nil {
-			return fmt.Errorf("failed to close log file: %v", err)
+	for _, f := range openedLogFiles() {
+		if f.isOpen() {
+			return true
 		}
-		logFile = nil
-		fileLogger = nil
 	}
-	return nil
+	return false
 }
 
-// setupCleanup sets up signal handling for graceful shutdown
-func setupCleanup() {
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		<-sigChan
-		if err := Close(); err != nil {
-			log.Printf("Error closing logger: %v", err)
-		}
-		os.Exit(0)
-	}()
+// Close closes every log file opened with OpenLogFile or LoggerConfig.LogToFile,
+// waiting for rotated files to be compressed. An Engine shutting down only
+// closes the files of its own Logger middleware.
+func Close() error {
+	var errs []error
+	for _, f := range openedLogFiles() {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
 }
@@ -136,9 +136,8 @@ func TestLoggerCleanup(t *testing.T) {
 	err = Close()
 	require.NoError(t, err, "Cleanup should succeed")
 
-	// Verify logger is reset
-	assert.Nil(t, fileLogger, "File logger should be nil after cleanup")
-	assert.Nil(t, logFile, "Log file should be nil after cleanup")
+	// Verify the file is closed
+	assert.False(t, IsFileLoggingEnabled(), "Log file should be closed after cleanup")
 }
 
 // TODO: test failing
@@ -197,6 +196,8 @@ func TestLoggerWithCustomIP(t *testing.T) {
 	r.Header.Set("X-Real-IP", "1.2.3.4")
 
 	c := NewContext(w, r)
+	c.engine = New()
+	c.engine.SetTrustedProxies("192.0.2.1") // the httptest RemoteAddr
 	handler := Logger()
 	handler(c)
 
@@ -250,11 +251,12 @@ func TestLoggerWithSkipPaths(t *testing.T) {
 }
 func TestLogLevels(t *testing.T) {
 	var buf bytes.Buffer
-	logger := &Log{logger: log.New(&buf, "", 0)}
+	logger := NewLog(NewConsoleHandler(&buf, nil).WithColor(true))
+	logger.exit = func(int) {}
 
 	tests := []struct {
 		level   LogLevel
-		method  func(...interface{})
+		method  func(string, ...any)
 		message string
 		color   string
 		prefix  string
@@ -281,7 +283,8 @@ func TestLogLevels(t *testing.T) {
 
 func TestLogfLevels(t *testing.T) {
 	var buf bytes.Buffer
-	logger := &Log{logger: log.New(&buf, "", 0)}
+	logger := NewLog(NewConsoleHandler(&buf, nil).WithColor(true))
+	logger.exit = func(int) {}
 
 	tests := []struct {
 		level  LogLevel
@@ -326,7 +329,7 @@ func TestInitFileLogger(t *testing.T) {
 		LogFilePath: logPath,
 	}
 
-	err := initFileLogger(config)
+	_, err := initFileLogger(config)
 	require.NoError(t, err)
 
 	// Verify file exists
@@ -25,6 +25,18 @@ type BaseClaims struct {
 	jwt.RegisteredClaims
 }
 
+// UserIDClaims is implemented by claims carrying a user ID. The Auth middleware
+// records it with Context.SetUserID, so logs show the user; for other claims
+// it uses the subject.
+type UserIDClaims interface {
+	GetUserID() string
+}
+
+// GetUserID returns the UserID claim
+func (c *BaseClaims) GetUserID() string {
+	return c.UserID
+}
+
 // AuthConfig defines the config for Auth middleware
 type AuthConfig struct {
 	// secret key used for signing tokens
@@ -120,8 +132,21 @@ func AuthWithConfig(config AuthConfig) zen.HandlerFunc {
 
 		// Setting claims to context
 		newCtx := c.WithValue(claimsKey{}, claims)
+		c.Ctx = newCtx.Ctx
 		c.Request = c.Request.WithContext(newCtx.Ctx)
+		c.SetUserID(claimsUserID(claims))
+	}
+}
+
+// claimsUserID returns the user ID of claims, or their subject
+func claimsUserID(claims jwt.Claims) string {
+	if c, ok := claims.(UserIDClaims); ok {
+		if id := c.GetUserID(); id != "" {
+			return id
+		}
 	}
+	subject, _ := claims.GetSubject()
+	return subject
 }
 
 func getToken(c *zen.Context, config AuthConfig) (string, error) {
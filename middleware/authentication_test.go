@@ -49,6 +49,34 @@ func TestAuth(t *testing.T) {
 			assert.Equal(t, "123", retrievedClaims.UserID)
 			assert.Equal(t, "user", retrievedClaims.Role)
 		}
+
+		// Handlers see the claims and the user ID
+		handlerClaims, ok := GetClaims[*BaseClaims](c)
+		if assert.True(t, ok) {
+			assert.Equal(t, "123", handlerClaims.UserID)
+		}
+		assert.Equal(t, "123", c.UserID())
+	})
+
+	t.Run("subject as user ID", func(t *testing.T) {
+		claims := &jwt.RegisteredClaims{
+			Subject:   "alice",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+		token, err := GenerateToken(claims, secretKey)
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		c := zen.NewContext(httptest.NewRecorder(), req)
+
+		config := DefaultAuthConfig()
+		config.SecretKey = secretKey
+		config.ClaimsFactory = func() jwt.Claims { return &jwt.RegisteredClaims{} }
+		c.Handlers = []zen.HandlerFunc{AuthWithConfig(config)}
+		c.Next()
+
+		assert.Equal(t, "alice", c.UserID())
 	})
 }
 func TestDefaultAuthConfig(t *testing.T) {
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ThembinkosiThemba/zen"
+)
+
+// custom errors
+var (
+	ErrMissingClientCert = errors.New("missing client certificate")
+	ErrCertNotAllowed    = errors.New("client certificate not allowed")
+)
+
+// CertAuthConfig defines the config for CertAuth middleware. A verified client
+// certificate is allowed if it matches any of the allowed identities or Authorize.
+type CertAuthConfig struct {
+	// AllowedSPIFFEIDs are the SPIFFE IDs allowed. An ID ending in "/*" allows
+	// every ID under it, e.g. "spiffe://example.org/ns/payments/*".
+	AllowedSPIFFEIDs []string
+
+	// AllowedSubjects are the subject common names allowed
+	AllowedSubjects []string
+
+	// AllowedDNSNames are the DNS subject alternative names allowed
+	AllowedDNSNames []string
+
+	// Authorize decides for certificates not matched by the lists above
+	Authorize func(c *zen.Context, cert *x509.Certificate) bool
+
+	// SkipPaths defines paths that should skip authorization
+	SkipPaths []string
+
+	// Unauthorized handles rejected requests, with ErrMissingClientCert or ErrCertNotAllowed.
+	// Default 401 without a certificate and 403 for a certificate not allowed.
+	Unauthorized func(*zen.Context, error)
+}
+
+// DefaultCertAuthConfig returns the default cert auth configuration
+func DefaultCertAuthConfig() CertAuthConfig {
+	return CertAuthConfig{
+		Unauthorized: func(c *zen.Context, err error) {
+			status := http.StatusForbidden
+			if errors.Is(err, ErrMissingClientCert) {
+				status = http.StatusUnauthorized
+			}
+			c.JSON(status, map[string]interface{}{
+				"error": err.Error(),
+			})
+		},
+	}
+}
+
+// CertAuth returns the CertAuth middleware allowing the given SPIFFE IDs. It
+// requires the server to verify client certificates, see zen.LoadTLSConfig.
+//
+// Usage:
+//
+//	internal := app.GroupRoutes("/internal")
+//	internal.Apply(middleware.CertAuth("spiffe://example.org/ns/prod/sa/billing"))
+func CertAuth(spiffeIDs ...string) zen.HandlerFunc {
+	config := DefaultCertAuthConfig()
+	config.AllowedSPIFFEIDs = spiffeIDs
+	return CertAuthWithConfig(config)
+}
+
+// CertAuthWithConfig returns the CertAuth middleware with custom config
+func CertAuthWithConfig(config CertAuthConfig) zen.HandlerFunc {
+	if config.Unauthorized == nil {
+		config.Unauthorized = DefaultCertAuthConfig().Unauthorized
+	}
+
+	return func(c *zen.Context) {
+		for _, path := range config.SkipPaths {
+			if path == c.Request.URL.Path {
+				return
+			}
+		}
+
+		cert := c.PeerCertificate()
+		if cert == nil {
+			config.Unauthorized(c, ErrMissingClientCert)
+			c.Quit()
+			return
+		}
+
+		if !certAllowed(c, cert, config) {
+			config.Unauthorized(c, ErrCertNotAllowed)
+			c.Quit()
+		}
+	}
+}
+
+func certAllowed(c *zen.Context, cert *x509.Certificate, config CertAuthConfig) bool {
+	if id := c.PeerSPIFFEID(); id != "" {
+		for _, allowed := range config.AllowedSPIFFEIDs {
+			if id == allowed || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(id, allowed[:len(allowed)-1])) {
+				return true
+			}
+		}
+	}
+	for _, allowed := range config.AllowedSubjects {
+		if cert.Subject.CommonName == allowed {
+			return true
+		}
+	}
+	for _, name := range cert.DNSNames {
+		for _, allowed := range config.AllowedDNSNames {
+			if strings.EqualFold(name, allowed) {
+				return true
+			}
+		}
+	}
+	return config.Authorize != nil && config.Authorize(c, cert)
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ThembinkosiThemba/zen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertAuth(t *testing.T) {
+	spiffeID, _ := url.Parse("spiffe://example.org/ns/payments/sa/api")
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "payments-api"},
+		DNSNames: []string{"api.payments.internal"},
+		URIs:     []*url.URL{spiffeID},
+	}
+
+	run := func(handler zen.HandlerFunc, cert *x509.Certificate) (*httptest.ResponseRecorder, bool) {
+		req := httptest.NewRequest(http.MethodGet, "/internal", nil)
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		rec := httptest.NewRecorder()
+		c := zen.NewContext(rec, req)
+
+		reached := false
+		c.Handlers = []zen.HandlerFunc{handler, func(c *zen.Context) { reached = true }}
+		c.Next()
+		return rec, reached
+	}
+
+	t.Run("allowed SPIFFE ID", func(t *testing.T) {
+		_, reached := run(CertAuth("spiffe://example.org/ns/payments/sa/api"), cert)
+		assert.True(t, reached)
+	})
+
+	t.Run("allowed SPIFFE ID prefix", func(t *testing.T) {
+		_, reached := run(CertAuth("spiffe://example.org/ns/payments/*"), cert)
+		assert.True(t, reached)
+	})
+
+	t.Run("SPIFFE ID not allowed", func(t *testing.T) {
+		rec, reached := run(CertAuth("spiffe://example.org/ns/billing/*"), cert)
+		assert.False(t, reached)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("missing certificate", func(t *testing.T) {
+		rec, reached := run(CertAuth("spiffe://example.org/ns/payments/*"), nil)
+		assert.False(t, reached)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("subject and DNS names", func(t *testing.T) {
+		_, reached := run(CertAuthWithConfig(CertAuthConfig{AllowedSubjects: []string{"payments-api"}}), cert)
+		assert.True(t, reached)
+
+		_, reached = run(CertAuthWithConfig(CertAuthConfig{AllowedDNSNames: []string{"API.payments.internal"}}), cert)
+		assert.True(t, reached)
+	})
+
+	t.Run("custom authorization", func(t *testing.T) {
+		config := CertAuthConfig{
+			Authorize: func(c *zen.Context, cert *x509.Certificate) bool {
+				return c.GetMethod() == http.MethodGet && cert.Subject.CommonName == "payments-api"
+			},
+		}
+		_, reached := run(CertAuthWithConfig(config), cert)
+		assert.True(t, reached)
+	})
+}
@@ -276,6 +276,13 @@ func sanitizeRequest(c *zen.Context, cfg SecurityConfig) error {
 		}
 	}
 
+	// Files are checked when the handler parses the body, with c.MultipartForm,
+	// c.FormFile, c.StreamMultipart or c.Request.FormFile, so the upload limits
+	// of group middleware such as zen.Uploads still apply
+	if len(cfg.AllowedFileTypes) > 0 {
+		c.SetAllowedFileTypes(cfg.AllowedFileTypes...)
+	}
+
 	if cfg.SQLInjectionCheck {
 		if checkSqlInjection(c.Request) {
 			return &securityError{
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThembinkosiThemba/zen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityMiddleware_AllowedFileTypes(t *testing.T) {
+	app := zen.New()
+	app.Apply(SecurityMiddleware(SecurityConfig{
+		Strategies:       RequestSanitization,
+		MaxRequestSize:   1 << 20,
+		AllowedFileTypes: []string{".pdf"},
+	}))
+	app.SetUploadConfig(zen.UploadConfig{MaxFileSize: 16})
+	upload := func(c *zen.Context) {
+		file, err := c.FormFile("document")
+		switch {
+		case errors.Is(err, zen.ErrFileTypeNotAllowed):
+			c.Text(http.StatusUnsupportedMediaType, err.Error())
+		case errors.Is(err, zen.ErrFileTooLarge):
+			c.Text(http.StatusRequestEntityTooLarge, err.Error())
+		case err != nil:
+			c.Text(http.StatusBadRequest, err.Error())
+		default:
+			c.Text(http.StatusOK, file.ContentType)
+		}
+	}
+	app.POST("/upload", upload)
+	// The larger limit of a group applies although the middleware runs first
+	media := app.GroupRoutes("/media")
+	media.Apply(zen.Uploads(zen.UploadConfig{MaxFileSize: 1 << 20}))
+	media.POST("/upload", upload)
+	// Parsing with the request's own methods is checked as well
+	app.POST("/raw", func(c *zen.Context) {
+		file, header, err := c.Request.FormFile("document")
+		switch {
+		case errors.Is(err, zen.ErrFileTypeNotAllowed):
+			c.Text(http.StatusUnsupportedMediaType, err.Error())
+		case err != nil:
+			c.Text(http.StatusBadRequest, err.Error())
+		default:
+			file.Close()
+			c.Text(http.StatusOK, header.Filename)
+		}
+	})
+
+	large := "%PDF-1.7\n" + strings.Repeat("x", 100)
+	tests := []struct {
+		name     string
+		path     string
+		filename string
+		content  string
+		expected int
+	}{
+		{"allowed", "/upload", "report.pdf", "%PDF-1.7\n", http.StatusOK},
+		{"wrong extension", "/upload", "image.png", "\x89PNG\r\n\x1a\n", http.StatusUnsupportedMediaType},
+		{"renamed executable", "/upload", "report.pdf", "MZ\x90\x00\x03", http.StatusUnsupportedMediaType},
+		{"over the engine limit", "/upload", "report.pdf", large, http.StatusRequestEntityTooLarge},
+		{"within the group limit", "/media/upload", "report.pdf", large, http.StatusOK},
+		{"group checks the type", "/media/upload", "image.png", "\x89PNG\r\n\x1a\n", http.StatusUnsupportedMediaType},
+		{"request FormFile allowed", "/raw", "report.pdf", "%PDF-1.7\n", http.StatusOK},
+		{"request FormFile executable", "/raw", "evil.exe", "MZ\x90\x00\x03", http.StatusUnsupportedMediaType},
+		{"request FormFile renamed executable", "/raw", "report.pdf", "MZ\x90\x00\x03", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			part, _ := mw.CreateFormFile("document", tt.filename)
+			part.Write([]byte(tt.content))
+			mw.Close()
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, &body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
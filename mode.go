@@ -3,10 +3,18 @@
 // on DevMode. For production releases, you can switch to release mode.
 // Realease mode will remove all useful development functions for a much cleaner
 // out on your console/terminal
+//
+// Each Engine has its own mode, so two engines in one process, or a library
+// embedding zen, don't affect each other. Until Engine.SetMode is called, an
+// engine follows the package default: the ZEN_MODE environment variable ("dev",
+// "production" or "test"), or the mode set with SetCurrentMode.
 package zen
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
 )
 
 // Mode represents the running mode of the Zen framework
@@ -18,24 +26,121 @@ const (
 
 	// Production disables features for better performance and organization
 	Production
+
+	// Test silences the banner, route table and colours, for engines started in tests
+	Test
 )
 
-var currentMode = DevMode
+// ModeEnv is the environment variable new engines read their mode from
+const ModeEnv = "ZEN_MODE"
+
+// currentMode is the default mode of engines and of the package-level logger
+var currentMode atomic.Int32
+
+// engineMode is the mode of an engine, the default mode until it is set
+type engineMode struct {
+	mode atomic.Int32 // the mode plus one, zero while unset
+}
+
+func (m *engineMode) get() Mode {
+	if v := m.mode.Load(); v != 0 {
+		return Mode(v - 1)
+	}
+	return GetMode()
+}
+
+func (m *engineMode) set(mode Mode) {
+	m.mode.Store(int32(mode) + 1)
+}
+
+func init() {
+	mode, err := ParseMode(os.Getenv(ModeEnv))
+	if err != nil {
+		Warnf("%v, using DevMode", err)
+	}
+	currentMode.Store(int32(mode))
+}
+
+// ParseMode parses a mode name: "dev" (or "development", "debug"), "production"
+// (or "prod", "release") or "test". An empty name is DevMode.
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "dev", "development", "debug":
+		return DevMode, nil
+	case "production", "prod", "release":
+		return Production, nil
+	case "test":
+		return Test, nil
+	}
+	return DevMode, fmt.Errorf("unknown %s %q", ModeEnv, name)
+}
+
+// String returns the name of the mode
+func (m Mode) String() string {
+	switch m {
+	case DevMode:
+		return "dev"
+	case Production:
+		return "production"
+	case Test:
+		return "test"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
 
-// SetCurrentMode sets the current mode that zen will be running on
+// SetCurrentMode sets the default mode, used by the package-level logger and by
+// every engine whose mode wasn't set with Engine.SetMode, including engines
+// created before the call.
 func SetCurrentMode(m Mode) {
-	currentMode = m
-	if currentMode == DevMode {
+	currentMode.Store(int32(m))
+	if m == DevMode {
 		Debug("Running in development mode - switch to Production for deployment")
 		Info("You can change the mode by: zen.SetCurrentMode(zen.Production)")
 		fmt.Println()
 	}
 }
+
 // Helper function used to get the mode for zen
 func GetMode() Mode {
-	return currentMode
+	return Mode(currentMode.Load())
 }
 
+// IsDevMode reports whether the default mode is DevMode
 func IsDevMode() bool {
-	return currentMode == DevMode
+	return GetMode() == DevMode
+}
+
+// SetMode sets the mode of the engine, which then no longer follows
+// SetCurrentMode. Call it before serving.
+//
+// Usage:
+//
+//	app := zen.New()
+//	app.SetMode(zen.Production)
+func (engine *Engine) SetMode(m Mode) {
+	engine.mode.set(m)
+}
+
+// Mode returns the mode of the engine
+func (engine *Engine) Mode() Mode {
+	return engine.mode.get()
+}
+
+// IsDevMode reports whether the engine runs in DevMode
+func (engine *Engine) IsDevMode() bool {
+	return engine.Mode() == DevMode
+}
+
+// Mode returns the mode of the engine serving the request, or the default mode
+// for a Context created outside an engine.
+func (c *Context) Mode() Mode {
+	if c.engine == nil {
+		return GetMode()
+	}
+	return c.engine.Mode()
+}
+
+// IsDevMode reports whether the request is served in DevMode
+func (c *Context) IsDevMode() bool {
+	return c.Mode() == DevMode
 }
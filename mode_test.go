@@ -0,0 +1,103 @@
+package zen
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]Mode{
+		"":           DevMode,
+		"dev":        DevMode,
+		"Production": Production,
+		"release":    Production,
+		" test ":     Test,
+	}
+	for name, want := range tests {
+		if got, err := ParseMode(name); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseMode("staging"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+func TestEngine_IndependentModes(t *testing.T) {
+	dev, prod := New(), New()
+	dev.SetMode(DevMode)
+	prod.SetMode(Production)
+
+	var devOut, prodOut bytes.Buffer
+	dev.SetLogHandler(NewConsoleHandler(&devOut, nil))
+	prod.SetLogHandler(NewConsoleHandler(&prodOut, nil))
+
+	dev.Logger().Debug("cache miss")
+	prod.Logger().Debug("cache miss")
+	if !strings.Contains(devOut.String(), "cache miss") {
+		t.Error("Expected debug messages in DevMode")
+	}
+	if prodOut.Len() != 0 {
+		t.Errorf("Expected no debug messages in Production, got %q", prodOut.String())
+	}
+	if GetMode() != DevMode {
+		t.Errorf("Expected the default mode to be unaffected, got %v", GetMode())
+	}
+
+	c := NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	c.engine = prod
+	if c.Mode() != Production || c.IsDevMode() {
+		t.Errorf("Expected the Context to follow its engine, got %v", c.Mode())
+	}
+}
+
+func TestEngine_FollowsCurrentMode(t *testing.T) {
+	defer currentMode.Store(currentMode.Load())
+
+	app, pinned := New(), New()
+	pinned.SetMode(Test)
+	SetCurrentMode(Production)
+
+	if app.Mode() != Production || app.IsDevMode() {
+		t.Errorf("Expected an engine created before SetCurrentMode to follow it, got %v", app.Mode())
+	}
+	if pinned.Mode() != Test {
+		t.Errorf("Expected SetMode to take precedence, got %v", pinned.Mode())
+	}
+
+	var out bytes.Buffer
+	app.SetLogHandler(NewConsoleHandler(&out, nil))
+	app.Logger().Debug("hidden")
+	if out.Len() != 0 {
+		t.Errorf("Expected no debug messages once the default is Production, got %q", out.String())
+	}
+}
+
+func TestEngine_TestMode(t *testing.T) {
+	engine := New()
+	engine.SetMode(Test)
+	engine.Apply(Logger())
+	engine.GET("/", func(c *Context) {
+		c.Text(http.StatusOK, "ok")
+	})
+
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+	engine.SetLogHandler(NewConsoleHandler(&out, nil).WithColor(true))
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	engine.Logger().Info("started")
+
+	if !strings.Contains(out.String(), "200") || !strings.Contains(out.String(), "started") {
+		t.Fatalf("Expected the request and message to be logged, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "\033[") {
+		t.Errorf("Expected no colours in Test mode, got %q", out.String())
+	}
+}
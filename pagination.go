@@ -0,0 +1,210 @@
+package zen
+
+// This file contains pagination helpers. They parse page/limit or cursor query
+// parameters, build pagination metadata for the response envelope and emit
+// RFC 8288 Link headers so clients can navigate between pages.
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPage  = errors.New("invalid page parameter")
+	ErrInvalidLimit = errors.New("invalid limit parameter")
+)
+
+// PaginationConfig defines the query parameters and limits used for pagination
+type PaginationConfig struct {
+	PageParam    string // Query parameter holding the page number. Default "page".
+	LimitParam   string // Query parameter holding the page size. Default "limit".
+	CursorParam  string // Query parameter holding an opaque cursor. Default "cursor".
+	DefaultLimit int    // Page size used when no limit is supplied. Default 20.
+	MaxLimit     int    // Largest page size a client may request. Default 100.
+}
+
+// DefaultPaginationConfig returns the default pagination configuration
+func DefaultPaginationConfig() PaginationConfig {
+	return PaginationConfig{
+		PageParam:    "page",
+		LimitParam:   "limit",
+		CursorParam:  "cursor",
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	}
+}
+
+// Page holds the pagination parameters parsed from a request
+type Page struct {
+	Number int    // 1-based page number, used for offset pagination
+	Limit  int    // Number of items per page
+	Cursor string // Opaque cursor, used for cursor pagination
+
+	cfg PaginationConfig
+}
+
+// Offset returns the number of items to skip for offset based pagination
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.Limit
+}
+
+// PageMeta is the pagination metadata returned in the response envelope
+type PageMeta struct {
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+
+	cfg PaginationConfig
+}
+
+// Meta builds offset pagination metadata for a result set of total items
+func (p Page) Meta(total int) PageMeta {
+	totalPages := 0
+	if p.Limit > 0 {
+		totalPages = (total + p.Limit - 1) / p.Limit
+	}
+	return PageMeta{
+		Page:       p.Number,
+		Limit:      p.Limit,
+		Total:      total,
+		TotalPages: totalPages,
+		cfg:        p.cfg,
+	}
+}
+
+// CursorMeta builds cursor pagination metadata. Empty cursors mean there is no
+// next or previous page.
+func (p Page) CursorMeta(next, prev string) PageMeta {
+	return PageMeta{
+		Limit:      p.Limit,
+		NextCursor: next,
+		PrevCursor: prev,
+		cfg:        p.cfg,
+	}
+}
+
+// GetPage parses the pagination query parameters of the request.
+// Missing values fall back to page 1 and the configured default limit, and
+// limits above MaxLimit are clamped.
+//
+// Usage:
+//
+//	page, err := c.GetPage()
+//	if err != nil {
+//	    c.Error(http.StatusBadRequest, err.Error())
+//	    return
+//	}
+//	users, total := db.ListUsers(page.Offset(), page.Limit)
+//	c.SuccessPage(http.StatusOK, users, page.Meta(total), "Users retrieved")
+func (c *Context) GetPage(config ...PaginationConfig) (Page, error) {
+	cfg := DefaultPaginationConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	page := Page{Number: 1, Limit: cfg.DefaultLimit, cfg: cfg}
+	query := c.Request.URL.Query()
+
+	if raw := query.Get(cfg.PageParam); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return page, ErrInvalidPage
+		}
+		page.Number = n
+	}
+
+	if raw := query.Get(cfg.LimitParam); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return page, ErrInvalidLimit
+		}
+		page.Limit = n
+	}
+
+	if cfg.MaxLimit > 0 && page.Limit > cfg.MaxLimit {
+		page.Limit = cfg.MaxLimit
+	}
+
+	page.Cursor = query.Get(cfg.CursorParam)
+	return page, nil
+}
+
+// SetLinkHeader writes an RFC 8288 Link header for the given pagination metadata.
+// Offset pagination produces first, prev, next and last relations; cursor
+// pagination produces prev and next. Behind a proxy trusted with
+// Engine.SetTrustedProxies, the links use the scheme and host it forwarded.
+func (c *Context) SetLinkHeader(meta PageMeta) {
+	cfg := meta.cfg
+	if cfg.PageParam == "" {
+		cfg = DefaultPaginationConfig()
+	}
+
+	var links []string
+	link := func(rel string, values map[string]string) {
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", c.pageURL(cfg, values), rel))
+	}
+	limit := strconv.Itoa(meta.Limit)
+
+	if meta.NextCursor != "" || meta.PrevCursor != "" {
+		if meta.PrevCursor != "" {
+			link("prev", map[string]string{cfg.CursorParam: meta.PrevCursor, cfg.LimitParam: limit})
+		}
+		if meta.NextCursor != "" {
+			link("next", map[string]string{cfg.CursorParam: meta.NextCursor, cfg.LimitParam: limit})
+		}
+	} else if meta.Page > 0 {
+		page := func(n int) map[string]string {
+			return map[string]string{cfg.PageParam: strconv.Itoa(n), cfg.LimitParam: limit}
+		}
+		link("first", page(1))
+		if meta.Page > 1 {
+			link("prev", page(meta.Page-1))
+		}
+		if meta.Page < meta.TotalPages {
+			link("next", page(meta.Page+1))
+		}
+		if meta.TotalPages > 0 {
+			link("last", page(meta.TotalPages))
+		}
+	}
+
+	if len(links) > 0 {
+		c.SetHeader("Link", strings.Join(links, ", "))
+	}
+}
+
+// SuccessPage writes a successful response carrying pagination metadata and the
+// matching Link header.
+func (c *Context) SuccessPage(status int, data interface{}, meta PageMeta, message string) {
+	c.SetLinkHeader(meta)
+	c.SuccessWithMeta(status, data, meta, message)
+}
+
+// pageURL returns the current request URL with the given query values replaced
+func (c *Context) pageURL(cfg PaginationConfig, values map[string]string) string {
+	query := c.Request.URL.Query()
+	// Offset and cursor parameters are mutually exclusive
+	query.Del(cfg.PageParam)
+	query.Del(cfg.CursorParam)
+	for k, v := range values {
+		query.Set(k, v)
+	}
+
+	scheme, host := c.requestOrigin()
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     c.Request.URL.Path,
+		RawQuery: query.Encode(),
+	}
+	if u.Host == "" {
+		return u.RequestURI()
+	}
+	return u.String()
+}
@@ -0,0 +1,127 @@
+package zen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContext_GetPage(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantPage  int
+		wantLimit int
+		wantErr   error
+	}{
+		{name: "defaults", query: "", wantPage: 1, wantLimit: 20},
+		{name: "explicit", query: "page=3&limit=10", wantPage: 3, wantLimit: 10},
+		{name: "clamped limit", query: "limit=500", wantPage: 1, wantLimit: 100},
+		{name: "bad page", query: "page=abc", wantErr: ErrInvalidPage},
+		{name: "zero limit", query: "limit=0", wantErr: ErrInvalidLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/items?"+tt.query, nil))
+			page, err := c.GetPage()
+			if err != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if page.Number != tt.wantPage || page.Limit != tt.wantLimit {
+				t.Errorf("Expected page %d limit %d, got page %d limit %d", tt.wantPage, tt.wantLimit, page.Number, page.Limit)
+			}
+		})
+	}
+}
+
+func TestContext_SuccessPage(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://example.com/items?page=2&limit=10&sort=name", nil)
+	c := NewContext(w, req)
+
+	page, err := c.GetPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Offset() != 10 {
+		t.Errorf("Expected offset 10, got %d", page.Offset())
+	}
+	c.SuccessPage(http.StatusOK, []string{"a"}, page.Meta(35), "Items retrieved")
+
+	link := w.Header().Get("Link")
+	for _, want := range []string{
+		`<http://example.com/items?limit=10&page=1&sort=name>; rel="first"`,
+		`<http://example.com/items?limit=10&page=1&sort=name>; rel="prev"`,
+		`<http://example.com/items?limit=10&page=3&sort=name>; rel="next"`,
+		`<http://example.com/items?limit=10&page=4&sort=name>; rel="last"`,
+	} {
+		if !strings.Contains(link, want) {
+			t.Errorf("Link header %q missing %q", link, want)
+		}
+	}
+
+	var resp struct {
+		Meta PageMeta `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Meta.Total != 35 || resp.Meta.TotalPages != 4 || resp.Meta.Page != 2 {
+		t.Errorf("Unexpected meta %+v", resp.Meta)
+	}
+}
+
+func TestContext_CursorLinks(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "http://example.com/feed?cursor=abc", nil))
+
+	page, _ := c.GetPage()
+	c.SetLinkHeader(page.CursorMeta("def", ""))
+
+	want := `<http://example.com/feed?cursor=def&limit=20>; rel="next"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Errorf("Expected Link %q, got %q", want, got)
+	}
+}
+
+func TestContext_LinksBehindProxy(t *testing.T) {
+	engine := New()
+	engine.SetTrustedProxies("10.0.0.0/8")
+	engine.GET("/feed", func(c *Context) {
+		page, _ := c.GetPage()
+		c.SetLinkHeader(page.CursorMeta("def", ""))
+	})
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"trusted proxy", "10.0.0.1:1234", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"}, "https://api.example.com/feed"},
+		{"appended by proxy", "10.0.0.1:1234", map[string]string{"X-Forwarded-Proto": "http, https", "X-Forwarded-Host": "evil.com, api.example.com"}, "https://api.example.com/feed"},
+		{"forwarded header", "10.0.0.1:1234", map[string]string{"Forwarded": `for=1.2.3.4;proto=https;host="api.example.com"`}, "https://api.example.com/feed"},
+		{"untrusted peer", "1.2.3.4:1234", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.com"}, "http://internal:8080/feed"},
+		{"invalid values", "10.0.0.1:1234", map[string]string{"X-Forwarded-Proto": "javascript", "X-Forwarded-Host": "evil.com/x"}, "http://internal:8080/feed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://internal:8080/feed?cursor=abc", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if got := w.Header().Get("Link"); !strings.HasPrefix(got, "<"+tt.want+"?") {
+				t.Errorf("Expected a link to %s, got %q", tt.want, got)
+			}
+		})
+	}
+}
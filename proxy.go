@@ -0,0 +1,224 @@
+package zen
+
+// This file contains client IP resolution behind reverse proxies. Forwarding
+// headers are only believed when the request comes from a trusted proxy, and
+// X-Forwarded-For and Forwarded chains are walked from the right, skipping
+// trusted proxies, so entries a client adds itself are never used. Headers set
+// by hosting platforms, such as CF-Connecting-IP, are only used when opted in.
+// The scheme and host a client used are resolved the same way, from the
+// headers of trusted proxies only.
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Headers set by hosting platforms that hold the client IP. Only use one if
+// the application can not be reached without going through the platform.
+const (
+	PlatformCloudflare      = "CF-Connecting-IP"
+	PlatformGoogleAppEngine = "X-Appengine-Remote-Addr"
+	PlatformFlyIO           = "Fly-Client-IP"
+	PlatformAkamai          = "True-Client-IP"
+)
+
+// SetTrustedProxies sets the proxies, as IPs or CIDRs, whose forwarding
+// headers GetClientIP and the pagination Link header trust. By default no proxy
+// is trusted and GetClientIP returns the address of the peer.
+//
+// Usage:
+//
+//	app.SetTrustedProxies("10.0.0.0/8", "192.168.1.10")
+func (engine *Engine) SetTrustedProxies(proxies ...string) error {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %v", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+
+	engine.trustedProxies = networks
+	return nil
+}
+
+// SetTrustedPlatform makes GetClientIP use the client IP header set by a
+// hosting platform, whatever proxy the request comes from.
+//
+// Usage:
+//
+//	app.SetTrustedPlatform(zen.PlatformCloudflare)
+func (engine *Engine) SetTrustedPlatform(headers ...string) {
+	engine.platformHeaders = headers
+}
+
+// clientIP resolves the client IP of the request, see GetClientIP
+func (c *Context) clientIP() string {
+	peer := stripPort(c.Request.RemoteAddr)
+	if c.engine == nil {
+		return peer
+	}
+
+	for _, header := range c.engine.platformHeaders {
+		if ip := parseForwardedIP(c.GetHeader(header)); ip != nil {
+			return ip.String()
+		}
+	}
+
+	if !c.engine.isTrustedProxy(net.ParseIP(peer)) {
+		return peer
+	}
+
+	if forwarded := c.Request.Header.Values("Forwarded"); len(forwarded) > 0 {
+		return c.engine.walkChain(peer, forwardedFor(forwarded))
+	}
+	if xff := c.Request.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		var chain []string
+		for _, value := range xff {
+			chain = append(chain, strings.Split(value, ",")...)
+		}
+		return c.engine.walkChain(peer, chain)
+	}
+	if ip := parseForwardedIP(c.GetHeader("X-Real-IP")); ip != nil {
+		return ip.String()
+	}
+	return peer
+}
+
+// walkChain returns the rightmost address in a forwarding chain that is not a
+// trusted proxy. If the chain holds an invalid entry the last valid address is
+// returned, and if every address is trusted the leftmost one.
+func (engine *Engine) walkChain(peer string, chain []string) string {
+	client := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseForwardedIP(chain[i])
+		if ip == nil {
+			return client
+		}
+		client = ip.String()
+		if !engine.isTrustedProxy(ip) {
+			return client
+		}
+	}
+	return client
+}
+
+// isTrustedProxy reports whether ip belongs to a trusted proxy
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range engine.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestOrigin returns the scheme and host the client addressed. Behind a
+// trusted proxy they come from the last Forwarded element, or else the last
+// X-Forwarded-Proto and X-Forwarded-Host values, which the proxy set.
+func (c *Context) requestOrigin() (scheme, host string) {
+	scheme, host = "http", c.Request.Host
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if c.engine == nil || !c.engine.isTrustedProxy(net.ParseIP(stripPort(c.Request.RemoteAddr))) {
+		return scheme, host
+	}
+
+	var proto, forwardedHost string
+	if forwarded := c.Request.Header.Values("Forwarded"); len(forwarded) > 0 {
+		proto = lastValue(forwardedParam(forwarded, "proto"))
+		forwardedHost = lastValue(forwardedParam(forwarded, "host"))
+	} else {
+		proto = lastValue(listValues(c.Request.Header.Values("X-Forwarded-Proto")))
+		forwardedHost = lastValue(listValues(c.Request.Header.Values("X-Forwarded-Host")))
+	}
+
+	if proto = strings.ToLower(strings.Trim(proto, `"`)); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	if forwardedHost = strings.Trim(forwardedHost, `"`); forwardedHost != "" && !strings.ContainsAny(forwardedHost, "/\\@?# ") {
+		host = forwardedHost
+	}
+	return scheme, host
+}
+
+// forwardedFor extracts the for= values of RFC 7239 Forwarded headers in order
+func forwardedFor(headers []string) []string {
+	return forwardedParam(headers, "for")
+}
+
+// forwardedParam extracts the values of a parameter of RFC 7239 Forwarded
+// headers in order, with an empty value for elements without it
+func forwardedParam(headers []string, name string) []string {
+	var chain []string
+	for _, header := range headers {
+		for _, element := range strings.Split(header, ",") {
+			value := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, name) {
+					value = v
+				}
+			}
+			// Keep elements without the parameter so the chain is not shortened
+			chain = append(chain, value)
+		}
+	}
+	return chain
+}
+
+// listValues splits comma separated header values into their elements
+func listValues(headers []string) []string {
+	var values []string
+	for _, header := range headers {
+		values = append(values, strings.Split(header, ",")...)
+	}
+	return values
+}
+
+// lastValue returns the last of values, trimmed, or an empty string
+func lastValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[len(values)-1])
+}
+
+// parseForwardedIP parses an address from a forwarding header, which may be
+// quoted, bracketed or carry a port. Returns nil for "unknown" and obfuscated
+// identifiers.
+func parseForwardedIP(value string) net.IP {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if value == "" {
+		return nil
+	}
+	return net.ParseIP(stripPort(value))
+}
+
+// stripPort removes the port from host:port, [host]:port and [host]
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
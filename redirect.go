@@ -0,0 +1,272 @@
+package zen
+
+// This file contains redirect helpers and flash messages. Redirects go through
+// Context.Writer so the status is tracked like any other response, and absolute
+// targets are checked against the request host and the Engine's allow-list to
+// prevent open redirects. Flash messages are kept in a cookie signed with the
+// Engine's secret key and shown once on the next request, which suits the
+// POST-redirect-GET pattern.
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidRedirectCode = errors.New("invalid redirect status code")
+	ErrRedirectNotAllowed  = errors.New("redirect target is not allowed")
+)
+
+// flashCookieName is the name of the cookie holding flash messages
+const flashCookieName = "zen_flash"
+
+// processSecret signs cookies of engines without a secret key. It changes on
+// every start, so their flash messages don't survive a restart.
+var processSecret = sync.OnceValue(func() []byte {
+	return []byte(rand.Text())
+})
+
+// FlashMessage is a one-time message shown after a redirect
+type FlashMessage struct {
+	Kind    string `json:"k"` // e.g. "success", "error"
+	Message string `json:"m"`
+}
+
+// SetAllowedRedirectHosts sets the hosts, besides the request's own host, that
+// Context.Redirect may send clients to. A leading "*." matches any subdomain.
+//
+// Usage:
+//
+//	app.SetAllowedRedirectHosts("accounts.example.com", "*.example.org")
+func (engine *Engine) SetAllowedRedirectHosts(hosts ...string) {
+	engine.redirectHosts = hosts
+}
+
+// SetSecretKey sets the key signing the cookies the engine writes, such as flash
+// messages, so clients can't forge them. Instances behind a load balancer need
+// the same key. Without one, a random key is used until the process exits.
+//
+// Usage:
+//
+//	app.SetSecretKey([]byte(os.Getenv("APP_SECRET")))
+func (engine *Engine) SetSecretKey(key []byte) {
+	engine.secretKey = key
+}
+
+// secret returns the key signing cookies
+func (c *Context) secret() []byte {
+	if c.engine != nil && len(c.engine.secretKey) > 0 {
+		return c.engine.secretKey
+	}
+	return processSecret()
+}
+
+// Redirect redirects the client to location with the given 3xx status code.
+// Relative locations are always allowed; absolute ones must point at the
+// request host or a host allowed with Engine.SetAllowedRedirectHosts.
+// Nothing is written if an error is returned.
+//
+// Usage:
+//
+//	if err := c.Redirect(http.StatusSeeOther, "/dashboard"); err != nil {
+//	    c.Error(http.StatusBadRequest, err.Error())
+//	}
+func (c *Context) Redirect(code int, location string) error {
+	if !isRedirectCode(code) {
+		return fmt.Errorf("%w: %d", ErrInvalidRedirectCode, code)
+	}
+	if !c.redirectAllowed(location) {
+		return fmt.Errorf("%w: %s", ErrRedirectNotAllowed, location)
+	}
+
+	http.Redirect(c.Writer, c.Request, location, code)
+	return nil
+}
+
+// RedirectToRoute redirects the client to a named route. params are alternating
+// parameter names and values, as for Engine.URL.
+//
+// Usage:
+//
+//	c.RedirectToRoute(http.StatusSeeOther, "users.show", "id", user.ID)
+func (c *Context) RedirectToRoute(code int, name string, params ...string) error {
+	if c.engine == nil {
+		return fmt.Errorf("no route named %q", name)
+	}
+	location, err := c.engine.URL(name, params...)
+	if err != nil {
+		return err
+	}
+	return c.Redirect(code, location)
+}
+
+// isRedirectCode reports whether code is a usable redirect status
+func isRedirectCode(code int) bool {
+	switch code {
+	case http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusFound,
+		http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// redirectAllowed reports whether location is a safe redirect target
+func (c *Context) redirectAllowed(location string) bool {
+	// Browsers treat backslashes like slashes, so "/\evil.com" is protocol relative
+	normalized := strings.ReplaceAll(location, `\`, "/")
+	if strings.ContainsAny(normalized, "\r\n") {
+		return false
+	}
+
+	u, err := url.Parse(normalized)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return true // a path on this host
+	}
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	host := u.Hostname()
+	if strings.EqualFold(u.Host, c.Request.Host) {
+		return true
+	}
+	if c.engine == nil {
+		return false
+	}
+	for _, allowed := range c.engine.redirectHosts {
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(strings.ToLower(host), strings.ToLower(allowed[1:])) {
+				return true
+			}
+		} else if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFlash adds a message that is available through Flashes on the next request.
+// It must be called before the response is written, since the messages travel
+// in a cookie; later messages are dropped with a warning.
+//
+// Usage:
+//
+//	app.POST("/users", func(c *zen.Context) {
+//	    // ... create the user
+//	    c.AddFlash("success", "User created")
+//	    c.Redirect(http.StatusSeeOther, "/users")
+//	})
+func (c *Context) AddFlash(kind, message string) {
+	if c.Writer.Written() {
+		c.Logger().Warn("flash message dropped, the response headers were already written", "kind", kind)
+		return
+	}
+	c.outgoingFlashes = append(c.outgoingFlashes, FlashMessage{Kind: kind, Message: message})
+	c.writeFlashCookie(c.outgoingFlashes)
+}
+
+// Flashes returns the flash messages set by the previous request and clears them,
+// so each message is only shown once.
+//
+// Usage:
+//
+//	app.GET("/users", func(c *zen.Context) {
+//	    c.HTML(http.StatusOK, "users/index", zen.M{"Flashes": c.Flashes()})
+//	})
+func (c *Context) Flashes() []FlashMessage {
+	if c.flashesRead {
+		return nil
+	}
+	c.flashesRead = true
+
+	cookie, err := c.GetCookie(flashCookieName)
+	if err != nil {
+		return nil
+	}
+
+	// Clear the cookie unless this request has added new messages
+	if len(c.outgoingFlashes) == 0 {
+		c.writeFlashCookie(nil)
+	}
+
+	data, ok := c.verifyCookie(cookie.Value)
+	if !ok {
+		return nil
+	}
+	var flashes []FlashMessage
+	if err := json.Unmarshal(data, &flashes); err != nil {
+		return nil
+	}
+	return flashes
+}
+
+// writeFlashCookie stores flashes in the flash cookie, or deletes it if there are none
+func (c *Context) writeFlashCookie(flashes []FlashMessage) {
+	cookie := &http.Cookie{
+		Name:     flashCookieName,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	if len(flashes) == 0 {
+		cookie.MaxAge = -1
+	} else {
+		data, _ := json.Marshal(flashes)
+		cookie.Value = c.signCookie(data)
+	}
+
+	// Replace any flash cookie already set on this response
+	header := c.Writer.Header()
+	cookies := header.Values("Set-Cookie")
+	header.Del("Set-Cookie")
+	for _, existing := range cookies {
+		if !strings.HasPrefix(existing, flashCookieName+"=") {
+			header.Add("Set-Cookie", existing)
+		}
+	}
+	c.SetCookie(cookie)
+}
+
+// signCookie encodes data as a cookie value followed by its HMAC-SHA256
+func (c *Context) signCookie(data []byte) string {
+	value := base64.RawURLEncoding.EncodeToString(data)
+	return value + "." + base64.RawURLEncoding.EncodeToString(c.cookieMAC(value))
+}
+
+// verifyCookie returns the data of a value made by signCookie, and whether its signature is valid
+func (c *Context) verifyCookie(value string) ([]byte, bool) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.cookieMAC(encoded)) {
+		return nil, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// cookieMAC returns the HMAC-SHA256 of a flash cookie value
+func (c *Context) cookieMAC(value string) []byte {
+	h := hmac.New(sha256.New, c.secret())
+	h.Write([]byte(flashCookieName + "=" + value))
+	return h.Sum(nil)
+}
@@ -0,0 +1,142 @@
+package zen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContext_Redirect(t *testing.T) {
+	engine := New()
+	engine.SetAllowedRedirectHosts("accounts.example.com", "*.example.org")
+
+	tests := []struct {
+		name     string
+		code     int
+		location string
+		wantErr  error
+	}{
+		{name: "relative path", code: http.StatusSeeOther, location: "/dashboard"},
+		{name: "same host", code: http.StatusFound, location: "http://example.com/home"},
+		{name: "allowed host", code: http.StatusFound, location: "https://accounts.example.com/login"},
+		{name: "allowed subdomain", code: http.StatusFound, location: "https://eu.example.org/"},
+		{name: "foreign host", code: http.StatusFound, location: "https://evil.com/", wantErr: ErrRedirectNotAllowed},
+		{name: "protocol relative", code: http.StatusFound, location: "//evil.com/", wantErr: ErrRedirectNotAllowed},
+		{name: "backslash", code: http.StatusFound, location: `/\evil.com`, wantErr: ErrRedirectNotAllowed},
+		{name: "javascript", code: http.StatusFound, location: "javascript:alert(1)", wantErr: ErrRedirectNotAllowed},
+		{name: "invalid code", code: http.StatusOK, location: "/", wantErr: ErrInvalidRedirectCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c := NewContext(w, httptest.NewRequest("POST", "http://example.com/form", nil))
+			c.engine = engine
+
+			err := c.Redirect(tt.code, tt.location)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				if c.Writer.Written() {
+					t.Error("Nothing should be written when the redirect is rejected")
+				}
+				return
+			}
+			if w.Code != tt.code || c.Writer.Status() != tt.code {
+				t.Errorf("Expected status code %d, got %d", tt.code, w.Code)
+			}
+			if w.Header().Get("Location") != tt.location {
+				t.Errorf("Expected Location %q, got %q", tt.location, w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestContext_RedirectToRoute(t *testing.T) {
+	engine := New()
+	engine.GET("/users/:id", func(c *Context) {}).Name("users.show")
+	engine.POST("/users", func(c *Context) {
+		c.RedirectToRoute(http.StatusSeeOther, "users.show", "id", "7")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/users", nil))
+
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/users/7" {
+		t.Errorf("Expected 303 to /users/7, got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestContext_Flashes(t *testing.T) {
+	engine := New()
+	engine.POST("/users", func(c *Context) {
+		c.AddFlash("success", "User created")
+		c.AddFlash("info", "Welcome email sent")
+		c.Redirect(http.StatusSeeOther, "/users")
+	})
+	var got []FlashMessage
+	engine.GET("/users", func(c *Context) {
+		got = c.Flashes()
+		c.Text(http.StatusOK, "users")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/users", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != flashCookieName {
+		t.Fatalf("Expected a single flash cookie, got %v", cookies)
+	}
+
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if len(got) != 2 || got[0] != (FlashMessage{"success", "User created"}) || got[1].Kind != "info" {
+		t.Errorf("Unexpected flashes %v", got)
+	}
+	cleared := w.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("Expected the flash cookie to be cleared, got %v", cleared)
+	}
+
+	// A forged cookie, or one signed with another key, is ignored
+	forged := &http.Cookie{Name: flashCookieName, Value: base64.RawURLEncoding.EncodeToString([]byte(`[{"k":"success","m":"Paid"}]`))}
+	other := New()
+	other.SetSecretKey([]byte("another key"))
+	c := NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	c.engine = other
+	signed := &http.Cookie{Name: flashCookieName, Value: c.signCookie([]byte(`[{"k":"success","m":"Paid"}]`))}
+	for _, cookie := range []*http.Cookie{forged, signed} {
+		req = httptest.NewRequest("GET", "/users", nil)
+		req.AddCookie(cookie)
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+		if len(got) != 0 {
+			t.Errorf("Expected the cookie %q to be rejected, got %v", cookie.Value, got)
+		}
+	}
+}
+
+func TestContext_AddFlashAfterWrite(t *testing.T) {
+	var out bytes.Buffer
+	engine := New()
+	engine.SetLogHandler(NewJSONHandler(&out, nil))
+	engine.POST("/users", func(c *Context) {
+		c.Text(http.StatusOK, "created")
+		c.AddFlash("success", "User created")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/users", nil))
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("Expected no flash cookie, got %v", w.Result().Cookies())
+	}
+	if !strings.Contains(out.String(), "flash message dropped") {
+		t.Errorf("Expected a warning, got %q", out.String())
+	}
+}
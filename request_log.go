@@ -0,0 +1,101 @@
+package zen
+
+// This file contains the request-scoped logger. c.Logger() returns the engine's
+// logger with the request ID, method, route, client IP and user ID added to
+// every message, the same fields the JSON access log has, so the lines a
+// handler logs can be tied to the request's access log line.
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// RequestIDHeader is the header the request ID is read from and echoed in
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the longest request ID accepted from a request
+const maxRequestIDLength = 128
+
+// RequestID returns the ID of the request: the X-Request-ID header sent by the
+// client or a proxy, or else a random ID, which is added to the response
+// headers if they haven't been written yet. A header longer than 128 bytes or
+// with characters other than letters, digits and "-_.:+/=" is replaced by a
+// random ID, so it can't forge log lines or bloat responses.
+func (c *Context) RequestID() string {
+	if c.requestID != "" {
+		return c.requestID
+	}
+	if id := c.GetHeader(RequestIDHeader); validRequestID(id) {
+		c.requestID = id
+	} else if id := c.Writer.Header().Get(RequestIDHeader); id != "" {
+		c.requestID = id
+	} else {
+		c.requestID = rand.Text()
+		if !c.Writer.Written() {
+			c.Writer.Header().Set(RequestIDHeader, c.requestID)
+		}
+	}
+	return c.requestID
+}
+
+// validRequestID reports whether id is a request ID that is safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		b := id[i]
+		switch {
+		case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		case strings.IndexByte("-_.:+/=", b) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SetUserID records the ID of the authenticated user, which Logger and the
+// access log include. The Auth middleware sets it from the token's claims.
+func (c *Context) SetUserID(id string) {
+	c.userID = id
+	c.logger = nil
+}
+
+// UserID returns the ID of the authenticated user, or an empty string
+func (c *Context) UserID() string {
+	return c.userID
+}
+
+// Logger returns the engine's logger with the request's correlation fields:
+// request_id, method, route, client_ip and, once authenticated, user_id.
+//
+// Usage:
+//
+//	app.GET("/orders/:id", func(c *zen.Context) {
+//	    c.Logger().Info("order loaded", "order", c.GetParam("id"))
+//	    // ... [INFO] order loaded request_id=... method=GET route=/orders/:id client_ip=10.0.0.1 user_id=42 order=7
+//	})
+func (c *Context) Logger() *Log {
+	if c.logger != nil {
+		return c.logger
+	}
+
+	base := defaultLogger()
+	if c.engine != nil {
+		base = c.engine.logger
+	}
+	args := []any{
+		"request_id", c.RequestID(),
+		"method", c.Request.Method,
+	}
+	if c.route != "" {
+		args = append(args, "route", c.route)
+	}
+	args = append(args, "client_ip", c.GetClientIP())
+	if c.userID != "" {
+		args = append(args, "user_id", c.userID)
+	}
+	c.logger = base.With(args...)
+	return c.logger
+}
@@ -0,0 +1,98 @@
+package zen
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestContext_RequestID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "from-proxy")
+	c := NewContext(httptest.NewRecorder(), req)
+	if c.RequestID() != "from-proxy" {
+		t.Errorf("Expected the request header, got %q", c.RequestID())
+	}
+
+	w := httptest.NewRecorder()
+	c = NewContext(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	id := c.RequestID()
+	if len(id) < 16 || c.RequestID() != id {
+		t.Errorf("Expected a stable generated ID, got %q", id)
+	}
+	if w.Header().Get(RequestIDHeader) != id {
+		t.Errorf("Expected the generated ID in the response, got %q", w.Header().Get(RequestIDHeader))
+	}
+
+	for _, invalid := range []string{"forged\nline", "a b", "<script>", strings.Repeat("x", 129)} {
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, invalid)
+		c = NewContext(w, req)
+		id = c.RequestID()
+		c.Success(http.StatusOK, nil, "ok")
+		if id == invalid || len(id) < 16 || w.Header().Get(RequestIDHeader) != id {
+			t.Errorf("Expected %q to be replaced by a generated ID, got %q", invalid, id)
+		}
+		if strings.Contains(w.Body.String(), invalid) || !strings.Contains(w.Body.String(), id) {
+			t.Errorf("Expected %q not to be echoed, got %s", invalid, w.Body.String())
+		}
+	}
+}
+
+func TestContext_Logger(t *testing.T) {
+	var out, access bytes.Buffer
+	log.SetOutput(&access)
+	defer log.SetOutput(os.Stderr)
+
+	engine := New()
+	engine.SetLogHandler(NewJSONHandler(&out, nil))
+	engine.Apply(Logger(LoggerConfig{Format: LogFormatJSON}))
+	engine.Apply(func(c *Context) {
+		c.Logger().Info("before auth")
+		c.SetUserID("42")
+	})
+	engine.GET("/orders/:id", func(c *Context) {
+		c.Logger().Info("order loaded", "order", c.GetParam("id"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", out.String())
+	}
+	var before, loaded, line map[string]interface{}
+	json.Unmarshal([]byte(lines[0]), &before)
+	json.Unmarshal([]byte(lines[1]), &loaded)
+	json.Unmarshal(access.Bytes(), &line)
+
+	id := w.Header().Get(RequestIDHeader)
+	want := map[string]interface{}{
+		"request_id": id,
+		"method":     "GET",
+		"route":      "/orders/:id",
+		"client_ip":  "10.0.0.1",
+		"user_id":    "42",
+		"order":      "7",
+	}
+	for key, value := range want {
+		if loaded[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, loaded[key])
+		}
+	}
+	if _, ok := before["user_id"]; ok || before["request_id"] != id {
+		t.Errorf("Expected the user to be added once known, got %v", before)
+	}
+	if id == "" || line["request_id"] != id || line["user_id"] != "42" {
+		t.Errorf("Expected the access log to carry the same request and user, got %v", line)
+	}
+}
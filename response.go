@@ -1,5 +1,11 @@
 package zen
 
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
 // This package has neccessay response functions and helpers for structuring response
 // data in api responses
 
@@ -13,44 +19,168 @@ const (
 	Failure                // Failure represents a failed operation or result.
 )
 
+// AppCodeInfo describes a registered application code.
+type AppCodeInfo struct {
+	Code    AppCode // The numeric application code sent in the "app_code" field.
+	Name    string  // A stable, machine readable name such as "USER_NOT_FOUND".
+	Status  int     // The HTTP status code used when responding with this code.
+	Message string  // The default message used when none is supplied.
+}
+
+var (
+	appCodesMu sync.RWMutex
+	appCodes   = map[AppCode]AppCodeInfo{
+		OK:      {Code: OK, Name: "OK", Status: http.StatusOK, Message: "OK"},
+		Failure: {Code: Failure, Name: "FAILURE", Status: http.StatusInternalServerError, Message: "Failure"},
+	}
+)
+
+// RegisterAppCode registers a domain specific application code together with the
+// HTTP status and default message that should be used when it is returned.
+// It panics if the code is already registered, mirroring http.ServeMux for duplicate patterns.
+//
+// Usage:
+//
+//	const ErrUserNotFound zen.AppCode = 1001
+//	zen.RegisterAppCode(ErrUserNotFound, "USER_NOT_FOUND", http.StatusNotFound, "User not found")
+func RegisterAppCode(code AppCode, name string, status int, message string) {
+	appCodesMu.Lock()
+	defer appCodesMu.Unlock()
+
+	if _, exists := appCodes[code]; exists {
+		panic(fmt.Sprintf("zen: app code %d is already registered", code))
+	}
+	appCodes[code] = AppCodeInfo{Code: code, Name: name, Status: status, Message: message}
+}
+
+// LookupAppCode returns the registration for the given code.
+func LookupAppCode(code AppCode) (AppCodeInfo, bool) {
+	appCodesMu.RLock()
+	defer appCodesMu.RUnlock()
+
+	info, ok := appCodes[code]
+	return info, ok
+}
+
+// String returns the registered name of the code, or its number if it is unknown.
+func (code AppCode) String() string {
+	if info, ok := LookupAppCode(code); ok {
+		return info.Name
+	}
+	return fmt.Sprintf("AppCode(%d)", int(code))
+}
+
 // Map is a shorthand for map[string]interface{} with additional helper methods
 type M map[string]interface{}
 
 // Response represents a standard data response structure
 type Response struct {
-	Status  int         `json:"status"`
-	Success AppCode     `json:"success"`
-	Data    interface{} `json:"data"`
-	Message string      `json:"message"`
+	Status    int         `json:"status"`
+	Success   AppCode     `json:"success"`
+	Code      string      `json:"code,omitempty"`     // Registered name of a domain app code
+	AppCode   AppCode     `json:"app_code,omitempty"` // Numeric domain app code, see Fail
+	Data      interface{} `json:"data"`
+	Message   string      `json:"message"`
+	Meta      interface{} `json:"meta,omitempty"`       // Extra metadata such as pagination
+	RequestID string      `json:"request_id,omitempty"` // Request ID, if one was supplied
+}
+
+// EnvelopeFunc converts a Response into the value that is encoded as the response body.
+// It allows an Engine to change the shape of every Success and Error response.
+//
+// Usage:
+//
+//	app.SetEnvelope(func(c *zen.Context, r zen.Response) interface{} {
+//	    return zen.M{"ok": r.Success == zen.OK, "result": r.Data, "error": r.Code}
+//	})
+type EnvelopeFunc func(c *Context, r Response) interface{}
+
+// DefaultEnvelope returns the Response unchanged.
+func DefaultEnvelope(c *Context, r Response) interface{} {
+	return r
+}
+
+// SetEnvelope sets the envelope used by Success, Error and their variants.
+// Passing nil restores DefaultEnvelope.
+func (engine *Engine) SetEnvelope(envelope EnvelopeFunc) {
+	engine.envelope = envelope
+}
+
+// respond fills in the request ID, applies the engine's envelope and writes the result
+func (c *Context) respond(r Response) {
+	if r.RequestID == "" {
+		if c.requestID != "" {
+			r.RequestID = c.requestID
+		} else if id := c.GetHeader(RequestIDHeader); validRequestID(id) {
+			r.RequestID = id
+		}
+	}
+
+	envelope := DefaultEnvelope
+	if c.engine != nil && c.engine.envelope != nil {
+		envelope = c.engine.envelope
+	}
+	c.JSON(r.Status, envelope(c, r))
 }
 
 // Response creates a successful data response
 func (c *Context) Success(status int, data interface{}, message string) {
-	response := Response{
+	c.respond(Response{
 		Status:  status,
 		Data:    data,
 		Success: OK,
 		Message: message,
-	}
-	c.JSON(status, response)
+	})
+}
+
+// SuccessWithMeta creates a successful data response carrying extra metadata
+func (c *Context) SuccessWithMeta(status int, data interface{}, meta interface{}, message string) {
+	c.respond(Response{
+		Status:  status,
+		Data:    data,
+		Success: OK,
+		Message: message,
+		Meta:    meta,
+	})
 }
 
 // Error creates an error response
 func (c *Context) Error(status int, message string, details ...interface{}) {
-	var response Response
+	response := Response{
+		Status:  status,
+		Success: Failure,
+		Message: message,
+	}
 	if len(details) > 0 {
-		response = Response{
-			Status:  status,
-			Success: Failure,
-			Data:    details[0],
-			Message: message,
-		}
-	} else {
-		response = Response{
-			Status:  status,
-			Success: Failure,
-			Message: message,
-		}
+		response.Data = details[0]
+	}
+	c.respond(response)
+}
+
+// Fail creates an error response from a registered AppCode. The HTTP status and
+// message are taken from the registration; unknown codes respond with 500.
+// "success" is Failure as for Error, the code is sent in "app_code" and its
+// name in "code".
+//
+// Usage:
+//
+//	c.Fail(ErrUserNotFound)
+//	c.Fail(ErrValidation, zen.M{"field": "email"})
+func (c *Context) Fail(code AppCode, details ...interface{}) {
+	info, ok := LookupAppCode(code)
+	if !ok {
+		info = AppCodeInfo{Code: code, Status: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)}
+	}
+
+	response := Response{
+		Status:  info.Status,
+		Success: Failure,
+		Code:    info.Name,
+		AppCode: code,
+		Message: info.Message,
+	}
+	if len(details) > 0 {
+		response.Data = details[0]
 	}
-	c.JSON(status, response)
+	c.respond(response)
 }
@@ -0,0 +1,83 @@
+package zen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_Success(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	c := NewContext(w, req)
+
+	c.Success(http.StatusOK, M{"name": "zen"}, "ok")
+
+	var resp Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Status != http.StatusOK || resp.Success != OK || resp.Message != "ok" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if resp.RequestID != "req-1" {
+		t.Errorf("Expected request ID %q, got %q", "req-1", resp.RequestID)
+	}
+}
+
+func TestEngine_SetEnvelope(t *testing.T) {
+	engine := New()
+	engine.SetEnvelope(func(c *Context, r Response) interface{} {
+		return M{"ok": r.Success == OK, "result": r.Data}
+	})
+	engine.GET("/test", func(c *Context) {
+		c.Success(http.StatusOK, "hello", "ignored")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body["ok"] != true || body["result"] != "hello" {
+		t.Errorf("Envelope not applied, got %v", body)
+	}
+	if _, exists := body["message"]; exists {
+		t.Error("Default envelope fields should not be present")
+	}
+}
+
+func TestContext_Fail(t *testing.T) {
+	const codeNotFound AppCode = 4040
+	RegisterAppCode(codeNotFound, "USER_NOT_FOUND", http.StatusNotFound, "User not found")
+
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/test", nil))
+	c.Fail(codeNotFound, M{"id": "42"})
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Success != Failure || resp.AppCode != codeNotFound || resp.Code != "USER_NOT_FOUND" || resp.Message != "User not found" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if codeNotFound.String() != "USER_NOT_FOUND" {
+		t.Errorf("Expected code name USER_NOT_FOUND, got %s", codeNotFound)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Registering a duplicate code should panic")
+		}
+	}()
+	RegisterAppCode(codeNotFound, "DUPLICATE", http.StatusConflict, "")
+}
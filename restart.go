@@ -0,0 +1,119 @@
+package zen
+
+// This file contains graceful restarts. With ServerConfig.GracefulRestart set,
+// Run starts a new copy of the binary on SIGUSR2 and hands it the
+// listening socket. The new process serves on the same socket from its first
+// request, so no connection is refused during a deploy. Once it reports ready,
+// the old process stops accepting, drains its in-flight requests and exits.
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Environment variables holding the descriptors passed to the new process
+const (
+	restartListenerEnv = "ZEN_RESTART_LISTENER_FD"
+	restartReadyEnv    = "ZEN_RESTART_READY_FD"
+)
+
+// DefaultRestartTimeout is how long a graceful restart waits for the new process to be ready
+const DefaultRestartTimeout = 30 * time.Second
+
+var ErrRestartNotReady = errors.New("new process exited before it was ready")
+
+// restart starts a new copy of the binary with the same arguments, passing it
+// listener, and waits until it is serving. The old process keeps serving if
+// the new one fails to start, so a broken deploy doesn't take the site down.
+func (e *Engine) restart(listener net.Listener) error {
+	filer, ok := listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return fmt.Errorf("cannot hand off a %T", listener)
+	}
+	file, err := filer.File()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		readyWriter.Close()
+		return err
+	}
+
+	// ExtraFiles start at descriptor 3
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), restartListenerEnv+"=3", restartReadyEnv+"=4")
+	cmd.ExtraFiles = []*os.File{file, readyWriter}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	readyWriter.Close()
+	if err != nil {
+		return err
+	}
+	go cmd.Wait()
+
+	timeout := e.serverConfig.RestartTimeout
+	if timeout <= 0 {
+		timeout = DefaultRestartTimeout
+	}
+	ready.SetReadDeadline(time.Now().Add(timeout))
+
+	// The new process writes a byte once serving, and its end of the pipe is
+	// closed if it exits first
+	if _, err := ready.Read(make([]byte, 1)); err != nil {
+		cmd.Process.Kill()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return fmt.Errorf("new process not ready within %v", timeout)
+		}
+		return ErrRestartNotReady
+	}
+
+	// The socket now belongs to the new process as well, closing ours must not remove it
+	if unix, ok := listener.(*net.UnixListener); ok {
+		unix.SetUnlinkOnClose(false)
+	}
+	e.logger.Infof("New process %d is ready, draining", cmd.Process.Pid)
+	return nil
+}
+
+// inheritedListener returns the listener handed over by the process that
+// started this one for a graceful restart, or nil.
+func inheritedListener() (net.Listener, error) {
+	fd, err := strconv.Atoi(os.Getenv(restartListenerEnv))
+	if err != nil {
+		return nil, nil
+	}
+	os.Unsetenv(restartListenerEnv)
+
+	file := os.NewFile(uintptr(fd), "zen-listener")
+	defer file.Close()
+	return net.FileListener(file)
+}
+
+// notifyReady tells the process that started this one that it is serving
+func notifyReady() {
+	fd, err := strconv.Atoi(os.Getenv(restartReadyEnv))
+	if err != nil {
+		return
+	}
+	os.Unsetenv(restartReadyEnv)
+
+	file := os.NewFile(uintptr(fd), "zen-ready")
+	file.Write([]byte{1})
+	file.Close()
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package zen
+
+import "os"
+
+// restartSignals trigger a graceful restart, which is not supported on this platform
+var restartSignals []os.Signal
@@ -0,0 +1,12 @@
+//go:build unix
+
+package zen
+
+import (
+	"os"
+	"syscall"
+)
+
+// restartSignals trigger a graceful restart. SIGHUP is left to the log files,
+// which reopen on it for logrotate.
+var restartSignals = []os.Signal{syscall.SIGUSR2}
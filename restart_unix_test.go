@@ -0,0 +1,136 @@
+//go:build unix
+
+package zen
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestEngine_GracefulRestart(t *testing.T) {
+	if os.Getenv("ZEN_RESTART_CHILD") == "1" {
+		// Running as the new process, serving until told to quit
+		ctx, cancel := context.WithCancel(context.Background())
+		engine := New()
+		engine.GET("/", func(c *Context) {
+			c.Text(http.StatusOK, "new")
+		})
+		engine.GET("/quit", func(c *Context) {
+			c.Text(http.StatusOK, "bye")
+			cancel()
+		})
+		if err := engine.Run(ctx, "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	engine := New()
+	config := DefaultServerConfig()
+	config.GracefulRestart = true
+	config.RestartTimeout = 10 * time.Second
+	engine.SetServerConfig(config)
+
+	inFlight := make(chan struct{})
+	engine.GET("/slow", func(c *Context) {
+		close(inFlight)
+		time.Sleep(200 * time.Millisecond)
+		c.Text(http.StatusOK, "old")
+	})
+
+	// The new process is this test binary, running only this test. os.Args is
+	// set before the engine starts since restart reads it.
+	t.Setenv("ZEN_RESTART_CHILD", "1")
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestEngine_GracefulRestart$"}
+	defer func() { os.Args = args }()
+
+	addr, result := runEngine(t, engine, context.Background())
+
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+	<-inFlight
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("Expected the old process to drain and return nil, got %v", err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("Timeout waiting for the restart")
+	}
+	if got := <-response; got != "old" {
+		t.Errorf("Expected the in-flight request to complete on the old server, got %q", got)
+	}
+
+	// The same address is now served by the new process
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	for _, path := range []string{"/", "/quit"} {
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if path == "/" && string(body) != "new" {
+			t.Errorf("Expected the new process to answer, got %q", body)
+		}
+	}
+}
+
+func TestEngine_GracefulRestartFailure(t *testing.T) {
+	if os.Getenv("ZEN_RESTART_CHILD") == "fail" {
+		os.Exit(1)
+	}
+
+	engine := New()
+	config := DefaultServerConfig()
+	config.GracefulRestart = true
+	engine.SetServerConfig(config)
+	engine.GET("/", func(c *Context) {
+		c.Text(http.StatusOK, "old")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, result := runEngine(t, engine, ctx)
+	defer func() {
+		cancel()
+		<-result
+	}()
+
+	t.Setenv("ZEN_RESTART_CHILD", "fail")
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestEngine_GracefulRestartFailure$"}
+	defer func() { os.Args = args }()
+
+	listener, err := engine.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if err := engine.restart(listener); err != ErrRestartNotReady {
+		t.Errorf("Expected %v, got %v", ErrRestartNotReady, err)
+	}
+
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("Expected the old process to keep serving, got %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,370 @@
+package zen
+
+// This file contains RotatingFile, the log file writer behind LoggerConfig.LogToFile
+// and OpenLogFile. It rotates the file by size and/or time, keeps a limited
+// number of rotated files, optionally gzipped, and reopens its path on SIGHUP so
+// logrotate can move the file away instead.
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp in the name of rotated files, e.g. zen-2024-01-02T15-04-05.000.log
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateConfig configures the rotation of a log file. The zero value never rotates.
+type RotateConfig struct {
+	// Rotate before the file grows past MaxSize bytes, 0 for no limit
+	MaxSize int64
+
+	// Rotate every Interval, e.g. 24 * time.Hour, 0 for never. Rotations happen at
+	// multiples of the interval, so daily files start at midnight UTC.
+	Interval time.Duration
+
+	// Number of rotated files to keep, 0 to keep all
+	MaxBackups int
+
+	// Remove rotated files older than MaxAge, 0 to keep all
+	MaxAge time.Duration
+
+	// Gzip rotated files
+	Compress bool
+}
+
+// RotatingFile is an io.Writer appending to a log file that is rotated as
+// configured by its RotateConfig. Rotated files are renamed after the time of
+// the rotation, e.g. logs/zen.log becomes logs/zen-2024-01-02T15-04-05.000.log.
+// It is safe for concurrent use.
+type RotatingFile struct {
+	path   string
+	config RotateConfig
+	now    func() time.Time // the clock, replaced in tests
+
+	mu           sync.Mutex
+	file         *os.File // nil until the first write and after Close
+	size         int64
+	nextRotation time.Time
+
+	mill   sync.WaitGroup // compression and removal of rotated files in progress
+	millMu sync.Mutex     // runs one of them at a time
+}
+
+var (
+	logFiles    = make(map[string]*RotatingFile) // the files opened with OpenLogFile by path
+	logFilesMu  sync.Mutex
+	watchReopen sync.Once
+)
+
+// OpenLogFile opens the log file at path for appending, creating it and its
+// directory if needed. Opening a path that is already open returns the same
+// RotatingFile, whose configuration is kept. Open log files are reopened on
+// SIGHUP; close a file you opened with its Close method or zen.Close.
+//
+// Usage:
+//
+//	file, err := zen.OpenLogFile("logs/app.log", zen.RotateConfig{
+//	    MaxSize:    100 << 20, // 100 MB
+//	    MaxBackups: 7,
+//	    Compress:   true,
+//	})
+//	app.SetLogHandler(zen.NewMultiHandler(zen.NewConsoleHandler(os.Stdout, nil), zen.NewJSONHandler(file, nil)))
+func OpenLogFile(path string, config RotateConfig) (*RotatingFile, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %v", err)
+	}
+
+	logFilesMu.Lock()
+	defer logFilesMu.Unlock()
+	if f, ok := logFiles[path]; ok {
+		return f, nil
+	}
+
+	f := &RotatingFile{path: path, config: config, now: time.Now}
+	f.mu.Lock()
+	err = f.open()
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	logFiles[path] = f
+
+	watchReopen.Do(func() {
+		if len(reopenSignals) == 0 {
+			return
+		}
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, reopenSignals...)
+		go func() {
+			for range signals {
+				if err := ReopenLogFiles(); err != nil {
+					Errorf("failed to reopen log files: %v", err)
+				}
+			}
+		}()
+	})
+	return f, nil
+}
+
+// Path returns the absolute path of the file
+func (f *RotatingFile) Path() string {
+	return f.path
+}
+
+// Write appends p to the file, rotating it first if it is due. A closed file is
+// opened again.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.rotationDue(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate renames the file after the current time and starts a new one
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+// Reopen closes the file and opens its path again. Tools like logrotate rename
+// the file and then signal the process, which calls Reopen on SIGHUP. SIGHUP
+// doesn't trigger a graceful restart, which uses SIGUSR2.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.close(); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Close closes the file and waits for rotated files to be compressed. Writing
+// to the file afterwards opens it again.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	err := f.close()
+	f.mu.Unlock()
+	f.mill.Wait()
+	return err
+}
+
+// isOpen reports whether the file is open
+func (f *RotatingFile) isOpen() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file != nil
+}
+
+// open opens the file for appending; f.mu must be held
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %v", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	if f.config.Interval > 0 {
+		f.nextRotation = f.now().Truncate(f.config.Interval).Add(f.config.Interval)
+	}
+	return nil
+}
+
+// close closes the file if it is open; f.mu must be held
+func (f *RotatingFile) close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close log file: %v", err)
+	}
+	return nil
+}
+
+// rotationDue reports whether the file must be rotated before writing n bytes
+func (f *RotatingFile) rotationDue(n int64) bool {
+	if f.config.MaxSize > 0 && f.size > 0 && f.size+n > f.config.MaxSize {
+		return true
+	}
+	return f.config.Interval > 0 && !f.now().Before(f.nextRotation)
+}
+
+// rotate renames the file, opens a new one and compresses and removes rotated
+// files in the background; f.mu must be held
+func (f *RotatingFile) rotate() error {
+	if err := f.close(); err != nil {
+		return err
+	}
+
+	now := f.now()
+	backup := f.backupName(now)
+	for fileExists(backup) || fileExists(backup+".gz") {
+		// A rotation in the same millisecond, use the next one
+		t, _ := f.backupTime(filepath.Base(backup))
+		backup = f.backupName(t.Add(time.Millisecond))
+	}
+	if err := os.Rename(f.path, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		f.open()
+		return fmt.Errorf("failed to rotate log file: %v", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.mill.Add(1)
+	go func() {
+		defer f.mill.Done()
+		f.millMu.Lock()
+		defer f.millMu.Unlock()
+		if f.config.Compress {
+			if err := compressLogFile(backup); err != nil {
+				Errorf("failed to compress log file: %v", err)
+			}
+		}
+		f.removeOldBackups(now.Add(-f.config.MaxAge))
+	}()
+	return nil
+}
+
+// backupName returns the path of the file rotated at t
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir, name := filepath.Split(f.path)
+	ext := filepath.Ext(name)
+	return filepath.Join(dir, strings.TrimSuffix(name, ext)+"-"+t.Format(backupTimeFormat)+ext)
+}
+
+// backupTime returns the time of a rotated file from its name, and whether the
+// name is one of a file rotated from f
+func (f *RotatingFile) backupTime(name string) (time.Time, bool) {
+	base := filepath.Base(f.path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	name = strings.TrimSuffix(name, ".gz")
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(backupTimeFormat, name[len(prefix):len(name)-len(ext)], time.Local)
+	return t, err == nil
+}
+
+// removeOldBackups removes the rotated files beyond MaxBackups or, with MaxAge,
+// older than cutoff. The cutoff is taken when rotating, as the clock belongs to f.mu.
+func (f *RotatingFile) removeOldBackups(cutoff time.Time) {
+	if f.config.MaxBackups <= 0 && f.config.MaxAge <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path string
+		time time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		if t, ok := f.backupTime(entry.Name()); ok && !entry.IsDir() {
+			backups = append(backups, backup{filepath.Join(filepath.Dir(f.path), entry.Name()), t})
+		}
+	}
+	// Newest first
+	sort.Slice(backups, func(i, j int) bool { return backups[i].time.After(backups[j].time) })
+
+	for i, b := range backups {
+		if (f.config.MaxBackups > 0 && i >= f.config.MaxBackups) || (f.config.MaxAge > 0 && b.time.Before(cutoff)) {
+			os.Remove(b.path)
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// compressLogFile gzips path to path.gz and removes path
+func compressLogFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// ReopenLogFiles reopens the log files opened with OpenLogFile, as on SIGHUP
+func ReopenLogFiles() error {
+	var errs []error
+	for _, f := range openedLogFiles() {
+		errs = append(errs, f.Reopen())
+	}
+	return errors.Join(errs...)
+}
+
+// openedLogFiles returns the files opened with OpenLogFile
+func openedLogFiles() []*RotatingFile {
+	logFilesMu.Lock()
+	defer logFilesMu.Unlock()
+	files := make([]*RotatingFile, 0, len(logFiles))
+	for _, f := range logFiles {
+		files = append(files, f)
+	}
+	return files
+}
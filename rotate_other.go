@@ -0,0 +1,8 @@
+//go:build !unix
+
+package zen
+
+import "os"
+
+// reopenSignals make the open log files reopen their path; there is no such signal on this platform
+var reopenSignals []os.Signal
@@ -0,0 +1,191 @@
+package zen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a clock starting at start that advances by step on every call
+func fakeClock(start time.Time, step time.Duration) func() time.Time {
+	now := start
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+// backups returns the names of the files in dir other than the log file
+func backups(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != "app.log" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFile_MaxSize(t *testing.T) {
+	dir := t.TempDir()
+	file, err := OpenLogFile(filepath.Join(dir, "app.log"), RotateConfig{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.now = fakeClock(time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local), time.Second)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	current, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if string(current) != "fourth\n" {
+		t.Errorf("Expected the last line in the log file, got %q", current)
+	}
+	names := backups(t, dir)
+	if len(names) != 2 {
+		t.Fatalf("Expected 2 rotated files to be kept, got %v", names)
+	}
+	third, _ := os.ReadFile(filepath.Join(dir, names[1]))
+	if !strings.HasPrefix(names[0], "app-2024-01-02T15-04-") || string(third) != "third\n" {
+		t.Errorf("Unexpected rotated files %v with %q", names, third)
+	}
+}
+
+func TestRotatingFile_IntervalAndCompress(t *testing.T) {
+	dir := t.TempDir()
+	file, err := OpenLogFile(filepath.Join(dir, "app.log"), RotateConfig{Interval: time.Hour, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Truncate(time.Hour).Add(20 * time.Minute)
+	file.now = func() time.Time { return now }
+	file.Reopen()
+
+	file.Write([]byte("one\n"))
+	now = now.Add(50 * time.Minute) // into the next hour
+	file.Write([]byte("two\n"))
+	now = now.Add(10 * time.Minute)
+	file.Write([]byte("three\n"))
+	file.Close()
+
+	names := backups(t, dir)
+	if len(names) != 1 || !strings.HasSuffix(names[0], ".log.gz") {
+		t.Fatalf("Expected a compressed rotated file, got %v", names)
+	}
+	gz, err := os.Open(filepath.Join(dir, names[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+	reader, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	if string(content) != "one\n" {
+		t.Errorf("Expected the first hour in the rotated file, got %q", content)
+	}
+}
+
+func TestRotatingFile_MaxAge(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "app-2000-01-01T00-00-00.000.log.gz")
+	unrelated := filepath.Join(dir, "other.log")
+	os.WriteFile(old, nil, 0644)
+	os.WriteFile(unrelated, nil, 0644)
+
+	file, err := OpenLogFile(filepath.Join(dir, "app.log"), RotateConfig{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("line\n"))
+	file.Rotate()
+	file.Close()
+
+	if fileExists(old) {
+		t.Error("Expected the old rotated file to be removed")
+	}
+	if !fileExists(unrelated) || len(backups(t, dir)) != 2 {
+		t.Errorf("Expected the new rotated file and other files to be kept, got %v", backups(t, dir))
+	}
+}
+
+func TestRotatingFile_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	file, err := OpenLogFile(path, RotateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// logrotate moves the file away, then signals the process
+	file.Write([]byte("before\n"))
+	os.Rename(path, path+".1")
+	if err := ReopenLogFiles(); err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("after\n"))
+
+	moved, _ := os.ReadFile(path + ".1")
+	current, _ := os.ReadFile(path)
+	if string(moved) != "before\n" || string(current) != "after\n" {
+		t.Errorf("Expected writes to go to the new file, got %q and %q", moved, current)
+	}
+
+	if same, _ := OpenLogFile(path, RotateConfig{MaxSize: 1}); same != file {
+		t.Error("Expected the open file to be returned for the same path")
+	}
+}
+
+func TestLogger_SeparateFiles(t *testing.T) {
+	dir := t.TempDir()
+	api := Logger(LoggerConfig{LogToFile: true, LogFilePath: filepath.Join(dir, "api.log")})
+	admin := Logger(LoggerConfig{LogToFile: true, LogFilePath: filepath.Join(dir, "admin.log"), Format: "${method} ${path}"})
+
+	api(NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil)))
+	admin(NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/settings", nil)))
+	Close()
+
+	apiLog, _ := os.ReadFile(filepath.Join(dir, "api.log"))
+	adminLog, _ := os.ReadFile(filepath.Join(dir, "admin.log"))
+	if !strings.Contains(string(apiLog), "GET") || strings.Contains(string(apiLog), "/settings") {
+		t.Errorf("Unexpected api log %q", apiLog)
+	}
+	if string(adminLog) != "POST /settings\n" {
+		t.Errorf("Unexpected admin log %q", adminLog)
+	}
+}
+
+func TestNewMultiHandler(t *testing.T) {
+	var text, json bytes.Buffer
+	logger := NewLog(NewMultiHandler(NewConsoleHandler(&text, nil), NewJSONHandler(&json, nil))).With("job", 7)
+	logger.Warn("retrying")
+
+	if !strings.Contains(text.String(), "[WARNING] retrying job=7") {
+		t.Errorf("Unexpected text output %q", text.String())
+	}
+	if !strings.Contains(json.String(), `"msg":"retrying","job":7`) {
+		t.Errorf("Unexpected JSON output %q", json.String())
+	}
+}
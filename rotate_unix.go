@@ -0,0 +1,11 @@
+//go:build unix
+
+package zen
+
+import (
+	"os"
+	"syscall"
+)
+
+// reopenSignals make the open log files reopen their path, as logrotate expects
+var reopenSignals = []os.Signal{syscall.SIGHUP}
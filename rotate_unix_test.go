@@ -0,0 +1,81 @@
+//go:build unix
+
+package zen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile_ReopenOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	file, err := OpenLogFile(path, RotateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	os.Rename(path, path+".1")
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !fileExists(path) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the log file to be reopened on SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEngine_SIGHUPDoesNotRestart(t *testing.T) {
+	if marker := os.Getenv("ZEN_RESTART_MARKER"); marker != "" {
+		// Started by a restart, which SIGHUP must not trigger
+		os.WriteFile(marker, nil, 0644)
+		os.Exit(1)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	marker := filepath.Join(dir, "restarted")
+	file, err := OpenLogFile(path, RotateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	engine := New()
+	config := DefaultServerConfig()
+	config.GracefulRestart = true
+	engine.SetServerConfig(config)
+	ctx, cancel := context.WithCancel(context.Background())
+	_, result := runEngine(t, engine, ctx)
+	defer func() {
+		cancel()
+		<-result
+	}()
+
+	t.Setenv("ZEN_RESTART_MARKER", marker)
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestEngine_SIGHUPDoesNotRestart$"}
+	defer func() { os.Args = args }()
+
+	os.Rename(path, path+".1")
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !fileExists(path) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the log file to be reopened on SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if fileExists(marker) {
+		t.Error("Expected SIGHUP not to restart the binary")
+	}
+}
@@ -17,6 +17,8 @@ type Router struct {
 	handlers map[string]map[string][]HandlerFunc
 	// globalMiddleware stores middleware that applies to all routes.
 	globalMiddleware []HandlerFunc
+	// bodyLimits stores per-route body limits indexed by HTTP method and route pattern
+	bodyLimits map[string]map[string]int64
 
 	validationConfig ValidationConfig
 }
@@ -54,6 +56,7 @@ func NewRouter() *Router {
 	return &Router{
 		handlers:         make(map[string]map[string][]HandlerFunc),
 		globalMiddleware: make([]HandlerFunc, 0, 10), // keeping the middleware that can be applied to 10
+		bodyLimits:       make(map[string]map[string]int64),
 		validationConfig: defaultValidation,
 	}
 }
@@ -95,7 +98,7 @@ func (group *RouterGroup) GroupRoutes(prefix string) *RouterGroup {
 // addRoute registers a new route with the given HTTP method, path pattern, and handler.
 // It combines global middleware, group middleware, and the route handler into a single
 // handler chain.
-func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) *RouteRef {
 	pattern := group.prefix + comp
 	handlers := group.combineHandlers(handler)
 
@@ -103,6 +106,7 @@ func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFu
 		group.engine.router.handlers[method] = make(map[string][]HandlerFunc)
 	}
 	group.engine.router.handlers[method][pattern] = handlers
+	return &RouteRef{engine: group.engine, Route: Route{Method: method, Path: pattern}}
 }
 
 // combineHandlers merges global middleware, group middleware, and route handlers
@@ -123,38 +127,38 @@ func (group *RouterGroup) combineHandlers(handlers ...HandlerFunc) []HandlerFunc
 }
 
 // GET registers a new GET route
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+func (group *RouterGroup) GET(pattern string, handler HandlerFunc) *RouteRef {
+	return group.addRoute("GET", pattern, handler)
 }
 
 // POST registers a new POST route
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+func (group *RouterGroup) POST(pattern string, handler HandlerFunc) *RouteRef {
+	return group.addRoute("POST", pattern, handler)
 }
 
 // PUT registers a new PUT route
-func (group *RouterGroup) PUT(pattern string, handler HandlerFunc) {
-	group.addRoute("PUT", pattern, handler)
+func (group *RouterGroup) PUT(pattern string, handler HandlerFunc) *RouteRef {
+	return group.addRoute("PUT", pattern, handler)
 }
 
 // DELETE registers a new DELETE route
-func (group *RouterGroup) DELETE(pattern string, handler HandlerFunc) {
-	group.addRoute("DELETE", pattern, handler)
+func (group *RouterGroup) DELETE(pattern string, handler HandlerFunc) *RouteRef {
+	return group.addRoute("DELETE", pattern, handler)
 }
 
 // PATCH registers a new PATCH route
-func (group *RouterGroup) PATCH(pattern string, handler HandlerFunc) {
-	group.addRoute("PATCH", pattern, handler)
+func (group *RouterGroup) PATCH(pattern string, handler HandlerFunc) *RouteRef {
+	return group.addRoute("PATCH", pattern, handler)
 }
 
 // OPTIONS registers a new OPTIONS route
-func (group *RouterGroup) OPTIONS(pattern string, handler HandlerFunc) {
-	group.addRoute("OPTIONS", pattern, handler)
+func (group *RouterGroup) OPTIONS(pattern string, handler HandlerFunc) *RouteRef {
+	return group.addRoute("OPTIONS", pattern, handler)
 }
 
 // HEAD registers a new HEAD route
-func (group *RouterGroup) HEAD(pattern string, handler HandlerFunc) {
-	group.addRoute("HEAD", pattern, handler)
+func (group *RouterGroup) HEAD(pattern string, handler HandlerFunc) *RouteRef {
+	return group.addRoute("HEAD", pattern, handler)
 }
 
 func (r *Router) writeNotFound(c *Context) {
@@ -178,6 +182,20 @@ func (r *Router) handle(c *Context) {
 		for pattern, handlers := range methodHandlers {
 			if params, ok := r.matchPath(pattern, path); ok {
 				c.Params = params
+				c.route = pattern
+				if limit, ok := r.bodyLimits[method][pattern]; ok {
+					c.bodyLimit = limit
+					c.routeLimit = true
+				}
+
+				// Reject bodies that are declared too large before any handler reads them
+				if c.contentTooLarge() {
+					c.Handlers = r.globalMiddleware
+					c.writeTooLarge()
+					c.Next()
+					return
+				}
+
 				c.Handlers = handlers
 				c.Next()
 				return
@@ -0,0 +1,288 @@
+package zen
+
+// This file contains the server configuration. ServerConfig covers the knobs of
+// http.Server plus the listener's TCP keep-alive, and is applied to every
+// server the Engine starts. It can be loaded from environment variables so the
+// same binary can be tuned per deployment.
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ServerConfig holds the configuration of the HTTP server
+type ServerConfig struct {
+	// ReadTimeout is the maximum duration for reading an entire request, including the body.
+	ReadTimeout time.Duration
+
+	// ReadHeaderTimeout is the maximum duration for reading request headers. Default 10 seconds.
+	ReadHeaderTimeout time.Duration
+
+	// WriteTimeout is the maximum duration before timing out writes of the response.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is the maximum time to wait for the next request on a keep-alive
+	// connection. Default 120 seconds.
+	IdleTimeout time.Duration
+
+	// ShutdownTimeout is how long Run waits for in-flight requests on shutdown. Default 10 seconds.
+	ShutdownTimeout time.Duration
+
+	// MaxHeaderBytes is the maximum size of request headers. Default 1MB.
+	MaxHeaderBytes int
+
+	// DisableKeepAlives closes connections after every request.
+	DisableKeepAlives bool
+
+	// TCPKeepAlive is the keep-alive period of accepted TCP connections.
+	// Zero uses the system default, negative disables TCP keep-alives.
+	TCPKeepAlive time.Duration
+
+	// AutoPort binds the next free port when the requested one is in use, trying
+	// up to 100 ports. Ignored in Production, where the server fails
+	// to start rather than come up on an unexpected port.
+	AutoPort bool
+
+	// GracefulRestart makes Run restart the binary on SIGUSR2, handing the
+	// listening socket to the new process and draining once it is ready.
+	GracefulRestart bool
+
+	// RestartTimeout is how long a graceful restart waits for the new process to be
+	// ready before giving up and serving on. Default 30 seconds.
+	RestartTimeout time.Duration
+
+	// H2C serves HTTP/2 without TLS to clients that connect with prior knowledge,
+	// as service mesh sidecars do, next to HTTP/1. Upgrade from HTTP/1.1 is not supported.
+	H2C bool
+
+	// HTTP2 tunes HTTP/2 connections, over TLS and with H2C: MaxConcurrentStreams,
+	// MaxReadFrameSize, the flow control windows and SendPingTimeout/PingTimeout for
+	// idle connections. Nil uses the net/http defaults.
+	HTTP2 *http.HTTP2Config
+
+	// TLSConfig is used by ServeTLS. Certificates set here make the cert and key files optional.
+	TLSConfig *tls.Config
+
+	// ErrorLog receives errors accepting connections and unexpected handler behaviour.
+	// Default the standard logger.
+	ErrorLog *log.Logger
+
+	// BaseContext returns the base context of requests on a listener.
+	BaseContext func(net.Listener) context.Context
+
+	// ConnContext can modify the context of a new connection.
+	ConnContext func(ctx context.Context, c net.Conn) context.Context
+
+	// ConnState is called when a connection changes state, e.g. to count open connections.
+	ConnState func(net.Conn, http.ConnState)
+}
+
+// DefaultServerConfig returns the default server configuration
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ShutdownTimeout:   DefaultShutdownTimeout,
+		RestartTimeout:    DefaultRestartTimeout,
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+	}
+}
+
+// ServerConfigFromEnv returns the default server configuration overridden by
+// environment variables. Names are the prefix, "ZEN_" if empty, followed by
+// READ_TIMEOUT, READ_HEADER_TIMEOUT, WRITE_TIMEOUT, IDLE_TIMEOUT,
+// SHUTDOWN_TIMEOUT, RESTART_TIMEOUT, TCP_KEEP_ALIVE (durations such as "30s"),
+// MAX_HEADER_BYTES, DISABLE_KEEP_ALIVES, AUTO_PORT, GRACEFUL_RESTART, H2C,
+// HTTP2_MAX_CONCURRENT_STREAMS, HTTP2_MAX_READ_FRAME_SIZE, HTTP2_PING_TIMEOUT
+// (SendPingTimeout) and HTTP2_PING_RESPONSE_TIMEOUT (PingTimeout).
+//
+// Usage:
+//
+//	// ZEN_READ_TIMEOUT=5s ZEN_WRITE_TIMEOUT=10s ./server
+//	config, err := zen.ServerConfigFromEnv("")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	app.SetServerConfig(config)
+func ServerConfigFromEnv(prefix string) (ServerConfig, error) {
+	if prefix == "" {
+		prefix = "ZEN_"
+	}
+	config := DefaultServerConfig()
+
+	durations := map[string]*time.Duration{
+		"READ_TIMEOUT":        &config.ReadTimeout,
+		"READ_HEADER_TIMEOUT": &config.ReadHeaderTimeout,
+		"WRITE_TIMEOUT":       &config.WriteTimeout,
+		"IDLE_TIMEOUT":        &config.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":    &config.ShutdownTimeout,
+		"RESTART_TIMEOUT":     &config.RestartTimeout,
+		"TCP_KEEP_ALIVE":      &config.TCPKeepAlive,
+	}
+	for name, field := range durations {
+		value, ok := os.LookupEnv(prefix + name)
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s%s: %v", prefix, name, err)
+		}
+		*field = d
+	}
+
+	if value, ok := os.LookupEnv(prefix + "MAX_HEADER_BYTES"); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %sMAX_HEADER_BYTES: %v", prefix, err)
+		}
+		config.MaxHeaderBytes = n
+	}
+	if value, ok := os.LookupEnv(prefix + "DISABLE_KEEP_ALIVES"); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %sDISABLE_KEEP_ALIVES: %v", prefix, err)
+		}
+		config.DisableKeepAlives = b
+	}
+	if value, ok := os.LookupEnv(prefix + "AUTO_PORT"); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %sAUTO_PORT: %v", prefix, err)
+		}
+		config.AutoPort = b
+	}
+	if value, ok := os.LookupEnv(prefix + "GRACEFUL_RESTART"); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %sGRACEFUL_RESTART: %v", prefix, err)
+		}
+		config.GracefulRestart = b
+	}
+	if value, ok := os.LookupEnv(prefix + "H2C"); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %sH2C: %v", prefix, err)
+		}
+		config.H2C = b
+	}
+
+	http2 := &http.HTTP2Config{}
+	http2Ints := map[string]*int{
+		"HTTP2_MAX_CONCURRENT_STREAMS": &http2.MaxConcurrentStreams,
+		"HTTP2_MAX_READ_FRAME_SIZE":    &http2.MaxReadFrameSize,
+	}
+	for name, field := range http2Ints {
+		value, ok := os.LookupEnv(prefix + name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s%s: %v", prefix, name, err)
+		}
+		*field = n
+		config.HTTP2 = http2
+	}
+	http2Durations := map[string]*time.Duration{
+		"HTTP2_PING_TIMEOUT":          &http2.SendPingTimeout,
+		"HTTP2_PING_RESPONSE_TIMEOUT": &http2.PingTimeout,
+	}
+	for name, field := range http2Durations {
+		value, ok := os.LookupEnv(prefix + name)
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s%s: %v", prefix, name, err)
+		}
+		*field = d
+		config.HTTP2 = http2
+	}
+
+	return config, nil
+}
+
+// SetServerConfig sets the configuration of the servers started by Serve,
+// ServeTLS and Run.
+//
+// Usage:
+//
+//	config := zen.DefaultServerConfig()
+//	config.ReadTimeout = 5 * time.Second
+//	config.WriteTimeout = 10 * time.Second
+//	app.SetServerConfig(config)
+func (engine *Engine) SetServerConfig(config ServerConfig) {
+	engine.serverConfig = config
+}
+
+// maxPortAttempts is the number of ports AutoPort tries
+const maxPortAttempts = 100
+
+// listen opens a TCP listener on addr with the configured keep-alive period.
+// The listener is what gets served, so the bound port can't be taken in between.
+func (e *Engine) listen(addr string) (net.Listener, error) {
+	lc := net.ListenConfig{KeepAlive: e.serverConfig.TCPKeepAlive}
+	listener, err := lc.Listen(context.Background(), "tcp", addr)
+	if err == nil || !errors.Is(err, syscall.EADDRINUSE) || !e.serverConfig.AutoPort {
+		return listener, err
+	}
+	if e.Mode() == Production {
+		e.logger.Errorf("address %s is in use, AutoPort is ignored in Production", addr)
+		return nil, err
+	}
+
+	host, portStr, splitErr := net.SplitHostPort(addr)
+	port, convErr := strconv.Atoi(portStr)
+	if splitErr != nil || convErr != nil {
+		return nil, err
+	}
+	for i := 1; i < maxPortAttempts && port+i <= 65535; i++ {
+		candidate := net.JoinHostPort(host, strconv.Itoa(port+i))
+		listener, nextErr := lc.Listen(context.Background(), "tcp", candidate)
+		if nextErr == nil {
+			e.logger.Warnf("Port %d is in use. Using port %d instead", port, port+i)
+			return listener, nil
+		}
+		if !errors.Is(nextErr, syscall.EADDRINUSE) {
+			return nil, nextErr
+		}
+	}
+	return nil, err
+}
+
+// applyServerConfig copies the server configuration onto server
+func (e *Engine) applyServerConfig(server *http.Server) {
+	config := e.serverConfig
+	server.ReadTimeout = config.ReadTimeout
+	server.ReadHeaderTimeout = config.ReadHeaderTimeout
+	server.WriteTimeout = config.WriteTimeout
+	server.IdleTimeout = config.IdleTimeout
+	server.MaxHeaderBytes = config.MaxHeaderBytes
+	server.TLSConfig = config.TLSConfig
+	server.HTTP2 = config.HTTP2
+	if config.H2C {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		server.Protocols = protocols
+	}
+	server.ErrorLog = config.ErrorLog
+	server.BaseContext = config.BaseContext
+	server.ConnContext = config.ConnContext
+	server.ConnState = config.ConnState
+	if config.DisableKeepAlives {
+		server.SetKeepAlivesEnabled(false)
+	}
+}
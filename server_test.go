@@ -0,0 +1,211 @@
+package zen
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServerConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_READ_TIMEOUT", "5s")
+	t.Setenv("APP_WRITE_TIMEOUT", "1m")
+	t.Setenv("APP_MAX_HEADER_BYTES", "4096")
+	t.Setenv("APP_DISABLE_KEEP_ALIVES", "true")
+
+	config, err := ServerConfigFromEnv("APP_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ReadTimeout != 5*time.Second || config.WriteTimeout != time.Minute {
+		t.Errorf("Expected timeouts 5s and 1m, got %v and %v", config.ReadTimeout, config.WriteTimeout)
+	}
+	if config.MaxHeaderBytes != 4096 || !config.DisableKeepAlives {
+		t.Errorf("Expected MaxHeaderBytes 4096 and keep-alives disabled, got %d and %v", config.MaxHeaderBytes, config.DisableKeepAlives)
+	}
+	if config.ReadHeaderTimeout != DefaultServerConfig().ReadHeaderTimeout {
+		t.Errorf("Unset variables should keep their defaults, got ReadHeaderTimeout %v", config.ReadHeaderTimeout)
+	}
+
+	t.Setenv("APP_H2C", "true")
+	t.Setenv("APP_HTTP2_MAX_CONCURRENT_STREAMS", "500")
+	t.Setenv("APP_HTTP2_PING_TIMEOUT", "30s")
+	t.Setenv("APP_HTTP2_PING_RESPONSE_TIMEOUT", "5s")
+	config, err = ServerConfigFromEnv("APP_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.H2C || config.HTTP2 == nil || config.HTTP2.MaxConcurrentStreams != 500 {
+		t.Errorf("Expected H2C with 500 concurrent streams, got %v and %+v", config.H2C, config.HTTP2)
+	}
+	if config.HTTP2.SendPingTimeout != 30*time.Second || config.HTTP2.PingTimeout != 5*time.Second {
+		t.Errorf("Expected ping timeouts 30s and 5s, got %v and %v", config.HTTP2.SendPingTimeout, config.HTTP2.PingTimeout)
+	}
+
+	t.Setenv("APP_IDLE_TIMEOUT", "forever")
+	if _, err := ServerConfigFromEnv("APP_"); err == nil {
+		t.Error("Expected an error for an invalid duration")
+	}
+}
+
+func TestEngine_ServerConfig(t *testing.T) {
+	engine := New()
+	engine.GET("/", func(c *Context) {
+		c.Text(http.StatusOK, "ok")
+	})
+
+	var mu sync.Mutex
+	states := map[http.ConnState]int{}
+	config := DefaultServerConfig()
+	config.DisableKeepAlives = true
+	config.ConnState = func(_ net.Conn, state http.ConnState) {
+		mu.Lock()
+		states[state]++
+		mu.Unlock()
+	}
+	engine.SetServerConfig(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, result := runEngine(t, engine, ctx)
+
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !resp.Close {
+		t.Error("Expected the connection to be closed with keep-alives disabled")
+	}
+
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if states[http.StateNew] == 0 || states[http.StateActive] == 0 {
+		t.Errorf("Expected the ConnState hook to see new and active connections, got %v", states)
+	}
+}
+
+func TestEngine_ListenStrictPort(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	addr := busy.Addr().String()
+
+	engine := New()
+	if _, err := engine.listen(addr); err == nil {
+		t.Fatal("Expected binding a port in use to fail without AutoPort")
+	}
+
+	config := DefaultServerConfig()
+	config.AutoPort = true
+	engine.SetServerConfig(config)
+
+	listener, err := engine.listen(addr)
+	if err != nil {
+		t.Fatalf("Expected AutoPort to find a free port in DevMode, got %v", err)
+	}
+	if listener.Addr().String() == addr {
+		t.Error("Expected a different port")
+	}
+	listener.Close()
+
+	engine.SetMode(Production)
+	if _, err := engine.listen(addr); err == nil {
+		t.Error("Expected AutoPort to be ignored in Production")
+	}
+}
+
+func TestEngine_ServeListener(t *testing.T) {
+	engine := New()
+	engine.GET("/", func(c *Context) {
+		c.Text(http.StatusOK, "ok")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- engine.ServeListener(listener)
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	if err := engine.Shutdown(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-result; err != http.ErrServerClosed {
+		t.Errorf("Expected %v, got %v", http.ErrServerClosed, err)
+	}
+}
+
+func TestEngine_H2C(t *testing.T) {
+	engine := New()
+	engine.GET("/", func(c *Context) {
+		c.DeclareTrailers("X-Checksum")
+		c.Text(http.StatusOK, "streamed")
+		c.SetTrailer("X-Checksum", "abc123")
+		c.SetTrailer("X-Undeclared", "late")
+	})
+
+	config := DefaultServerConfig()
+	config.H2C = true
+	config.HTTP2 = &http.HTTP2Config{MaxConcurrentStreams: 10}
+	engine.SetServerConfig(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, result := runEngine(t, engine, ctx)
+	defer func() {
+		cancel()
+		<-result
+	}()
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	resp, err := client.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2 without TLS, got %s", resp.Proto)
+	}
+	if string(body) != "streamed" {
+		t.Errorf("Expected body %q, got %q", "streamed", body)
+	}
+	if resp.Trailer.Get("X-Checksum") != "abc123" || resp.Trailer.Get("X-Undeclared") != "late" {
+		t.Errorf("Expected trailers to be sent, got %v", resp.Trailer)
+	}
+
+	// HTTP/1 clients are still served
+	resp, err = http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.ProtoMajor != 1 || resp.Trailer.Get("X-Checksum") != "abc123" {
+		t.Errorf("Expected trailers over HTTP/1.1, got %s %v", resp.Proto, resp.Trailer)
+	}
+}
@@ -0,0 +1,428 @@
+package zen
+
+// This file implements Server-Sent Events. c.SSE() turns a request into an
+// event stream, and Hub fans published events out to subscribers of a topic.
+// Every subscriber has a bounded buffer; a subscriber that cannot keep up is
+// evicted instead of slowing down the publisher, and can resume with the
+// Last-Event-ID header when the browser reconnects.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSSEClosed = errors.New("event stream is closed")
+	ErrHubClosed = errors.New("hub is closed")
+)
+
+// SSEvent is a single Server-Sent Event.
+type SSEvent struct {
+	ID    string        // Optional event ID, sent back by the browser as Last-Event-ID
+	Event string        // Optional event type, defaults to "message" in the browser
+	Data  interface{}   // Event payload. Strings and []byte are sent as-is, anything else as JSON
+	Retry time.Duration // Optional reconnection delay for the browser
+}
+
+// SSEWriter writes Server-Sent Events to a client.
+type SSEWriter struct {
+	c           *Context
+	mu          sync.Mutex // guards writes and lastEventID
+	lastEventID string
+}
+
+// SSE prepares the response for Server-Sent Events and returns a writer for the stream.
+// The status and headers are sent immediately so the client sees the stream open.
+//
+// Usage:
+//
+//	app.GET("/events", func(c *zen.Context) {
+//	    stream := c.SSE()
+//	    for update := range updates {
+//	        if err := stream.Send(zen.SSEvent{Event: "update", Data: update}); err != nil {
+//	            return
+//	        }
+//	    }
+//	})
+func (c *Context) SSE() *SSEWriter {
+	c.SetContentType("text/event-stream")
+	c.SetHeader("Cache-Control", "no-cache")
+	c.SetHeader("X-Accel-Buffering", "no") // disable proxy buffering in nginx
+	c.Writer.WriteHeader(http.StatusOK)
+	c.flush()
+
+	return &SSEWriter{
+		c:           c,
+		lastEventID: c.GetHeader("Last-Event-ID"),
+	}
+}
+
+// LastEventID returns the ID of the last event the client received before it
+// reconnected, as sent in the Last-Event-ID header.
+func (s *SSEWriter) LastEventID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastEventID
+}
+
+// Done returns a channel that is closed when the client disconnects.
+func (s *SSEWriter) Done() <-chan struct{} {
+	return s.c.Done()
+}
+
+// Send writes an event to the client and flushes it.
+func (s *SSEWriter) Send(event SSEvent) error {
+	var b strings.Builder
+
+	if event.ID != "" {
+		b.WriteString("id: ")
+		b.WriteString(stripNewlines(event.ID))
+		b.WriteByte('\n')
+	}
+	if event.Event != "" {
+		b.WriteString("event: ")
+		b.WriteString(stripNewlines(event.Event))
+		b.WriteByte('\n')
+	}
+	if event.Retry > 0 {
+		b.WriteString("retry: ")
+		b.WriteString(strconv.FormatInt(event.Retry.Milliseconds(), 10))
+		b.WriteByte('\n')
+	}
+
+	data, err := encodeSSEData(event.Data)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: ")
+		b.WriteString(strings.TrimSuffix(line, "\r"))
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+
+	return s.write(b.String(), event.ID)
+}
+
+// Data sends an event with only a data field.
+func (s *SSEWriter) Data(data interface{}) error {
+	return s.Send(SSEvent{Data: data})
+}
+
+// Comment sends a comment line, which clients ignore. It is typically used as
+// a heartbeat to keep idle connections and proxies from timing out.
+func (s *SSEWriter) Comment(text string) error {
+	return s.write(": "+stripNewlines(text)+"\n\n", "")
+}
+
+// Retry tells the client how long to wait before reconnecting.
+func (s *SSEWriter) Retry(d time.Duration) error {
+	return s.write(fmt.Sprintf("retry: %d\n\n", d.Milliseconds()), "")
+}
+
+// Run sends every event received on events until the channel is closed or the
+// client disconnects, writing a heartbeat comment whenever the stream has been
+// idle for the heartbeat interval. A zero interval disables heartbeats.
+// Run returns nil when events is closed and ErrSSEClosed when the client goes away.
+func (s *SSEWriter) Run(events <-chan SSEvent, heartbeat time.Duration) error {
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.Done():
+			return ErrSSEClosed
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := s.Send(event); err != nil {
+				return err
+			}
+		case <-tick:
+			if err := s.Comment("heartbeat"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// write writes raw stream data and flushes it to the client
+func (s *SSEWriter) write(data, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.c.Err() != nil {
+		return ErrSSEClosed
+	}
+	if _, err := s.c.Writer.Write([]byte(data)); err != nil {
+		return err
+	}
+	s.c.flush()
+	if id != "" {
+		s.lastEventID = id
+	}
+	return nil
+}
+
+// encodeSSEData converts an event payload to the text sent in its data lines
+func encodeSSEData(data interface{}) (string, error) {
+	switch v := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	}
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// HubConfig holds the configuration for a Hub
+type HubConfig struct {
+	// BufferSize is the number of events buffered per subscriber before it is
+	// considered a slow consumer and evicted. Default 64.
+	BufferSize int
+
+	// History is the number of recent events kept per topic so reconnecting
+	// clients can resume from their Last-Event-ID. Default 0 (no history).
+	History int
+
+	// Heartbeat is the idle interval after which Handler sends a heartbeat comment.
+	// Default 15 seconds.
+	Heartbeat time.Duration
+
+	// OnEvict is called when a slow subscriber is evicted from a topic.
+	OnEvict func(topic string)
+}
+
+// DefaultHubConfig returns the default Hub configuration
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		BufferSize: 64,
+		Heartbeat:  15 * time.Second,
+	}
+}
+
+// Hub is an in-process publish/subscribe hub for Server-Sent Events.
+type Hub struct {
+	config  HubConfig
+	mu      sync.RWMutex
+	topics  map[string]map[*Subscription]struct{}
+	history map[string][]SSEvent
+	nextID  uint64
+	closed  bool
+}
+
+// Subscription receives the events published to the topics it subscribed to.
+type Subscription struct {
+	// C delivers the events. It is closed when the subscription is closed or evicted.
+	C <-chan SSEvent
+
+	ch      chan SSEvent
+	hub     *Hub
+	topics  []string
+	evicted bool
+	once    sync.Once
+}
+
+// NewHub creates a new Hub
+//
+// Usage:
+//
+//	hub := zen.NewHub()
+//	app.GET("/events/:topic", func(c *zen.Context) {
+//	    hub.Serve(c, c.GetParam("topic"))
+//	})
+//	hub.Publish("orders", zen.SSEvent{Event: "created", Data: order})
+func NewHub(config ...HubConfig) *Hub {
+	cfg := DefaultHubConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultHubConfig().BufferSize
+	}
+
+	return &Hub{
+		config:  cfg,
+		topics:  make(map[string]map[*Subscription]struct{}),
+		history: make(map[string][]SSEvent),
+	}
+}
+
+// Subscribe subscribes to the given topics.
+func (h *Hub) Subscribe(topics ...string) (*Subscription, error) {
+	return h.SubscribeFrom("", topics...)
+}
+
+// SubscribeFrom subscribes to the given topics and first replays the events
+// from the topic history that were published after lastEventID.
+func (h *Hub) SubscribeFrom(lastEventID string, topics ...string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	ch := make(chan SSEvent, h.config.BufferSize)
+	sub := &Subscription{C: ch, ch: ch, hub: h, topics: topics}
+
+	for _, topic := range topics {
+		if lastEventID != "" {
+			for _, event := range h.replay(topic, lastEventID) {
+				select {
+				case ch <- event:
+				default:
+				}
+			}
+		}
+
+		if h.topics[topic] == nil {
+			h.topics[topic] = make(map[*Subscription]struct{})
+		}
+		h.topics[topic][sub] = struct{}{}
+	}
+
+	return sub, nil
+}
+
+// Publish sends an event to every subscriber of the topic and returns the number
+// of subscribers it was delivered to. Events without an ID are given one.
+// Subscribers whose buffer is full are evicted.
+func (h *Hub) Publish(topic string, event SSEvent) int {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return 0
+	}
+
+	h.nextID++
+	if event.ID == "" {
+		event.ID = strconv.FormatUint(h.nextID, 10)
+	}
+
+	if h.config.History > 0 {
+		history := append(h.history[topic], event)
+		if len(history) > h.config.History {
+			history = history[len(history)-h.config.History:]
+		}
+		h.history[topic] = history
+	}
+
+	delivered, evicted := 0, 0
+	for sub := range h.topics[topic] {
+		select {
+		case sub.ch <- event:
+			delivered++
+		default:
+			sub.evicted = true
+			h.remove(sub)
+			evicted++
+		}
+	}
+	h.mu.Unlock()
+
+	// Called without the lock, so the callback can use the hub
+	if h.config.OnEvict != nil {
+		for i := 0; i < evicted; i++ {
+			h.config.OnEvict(topic)
+		}
+	}
+	return delivered
+}
+
+// Subscribers returns the number of subscribers of a topic.
+func (h *Hub) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Close closes every subscription. Publish and Subscribe fail after Close.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.topics {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// Serve subscribes the request to the given topics and streams events to it
+// until the client disconnects, the subscriber is evicted or the hub is closed.
+// Clients reconnecting with a Last-Event-ID header resume from the topic history.
+// On a closed hub nothing is written and ErrHubClosed is returned, so the
+// handler can respond, e.g. with 503 Service Unavailable.
+func (h *Hub) Serve(c *Context, topics ...string) error {
+	sub, err := h.SubscribeFrom(c.GetHeader("Last-Event-ID"), topics...)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	return c.SSE().Run(sub.C, h.config.Heartbeat)
+}
+
+// replay returns the history of a topic after the event with the given ID.
+// The caller must hold the lock.
+func (h *Hub) replay(topic, lastEventID string) []SSEvent {
+	history := h.history[topic]
+	for i, event := range history {
+		if event.ID == lastEventID {
+			return history[i+1:]
+		}
+	}
+	return nil
+}
+
+// remove unsubscribes sub from all its topics and closes its channel.
+// The caller must hold the lock.
+func (h *Hub) remove(sub *Subscription) {
+	for _, topic := range sub.topics {
+		if subs := h.topics[topic]; subs != nil {
+			delete(subs, sub)
+			if len(subs) == 0 {
+				delete(h.topics, topic)
+			}
+		}
+	}
+	sub.once.Do(func() { close(sub.ch) })
+}
+
+// Close unsubscribes from all topics.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Evicted reports whether the subscription was dropped for being too slow.
+func (s *Subscription) Evicted() bool {
+	s.hub.mu.RLock()
+	defer s.hub.mu.RUnlock()
+	return s.evicted
+}
@@ -0,0 +1,161 @@
+package zen
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContext_SSE(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "7")
+	c := NewContext(w, req)
+
+	stream := c.SSE()
+	if stream.LastEventID() != "7" {
+		t.Errorf("Expected Last-Event-ID %q, got %q", "7", stream.LastEventID())
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Error("Content-Type header should be text/event-stream")
+	}
+
+	if err := stream.Send(SSEvent{ID: "8", Event: "update", Data: "line1\nline2", Retry: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Data(M{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Comment("ping"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id: 8\nevent: update\nretry: 1000\ndata: line1\ndata: line2\n\n" +
+		"data: {\"n\":1}\n\n" +
+		": ping\n\n"
+	if w.Body.String() != want {
+		t.Errorf("Expected stream %q, got %q", want, w.Body.String())
+	}
+	if stream.LastEventID() != "8" {
+		t.Errorf("Expected last event ID to advance to 8, got %q", stream.LastEventID())
+	}
+}
+
+func TestHub_PublishAndEvict(t *testing.T) {
+	evicted := make(chan string, 1)
+	var hub *Hub
+	hub = NewHub(HubConfig{BufferSize: 1, OnEvict: func(topic string) {
+		// The callback can use the hub
+		hub.Subscribers(topic)
+		evicted <- topic
+	}})
+
+	sub, err := hub.Subscribe("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := hub.Publish("orders", SSEvent{Data: "first"}); n != 1 {
+		t.Errorf("Expected delivery to 1 subscriber, got %d", n)
+	}
+
+	// The buffer is full, so the next publish evicts the subscriber
+	hub.Publish("orders", SSEvent{Data: "second"})
+	if topic := <-evicted; topic != "orders" {
+		t.Errorf("Expected eviction from orders, got %q", topic)
+	}
+	if !sub.Evicted() {
+		t.Error("Subscription should be marked as evicted")
+	}
+	if hub.Subscribers("orders") != 0 {
+		t.Error("Evicted subscriber should be removed from the topic")
+	}
+
+	event, ok := <-sub.C
+	if !ok || event.Data != "first" {
+		t.Errorf("Expected buffered event to be delivered, got %+v", event)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Error("Subscription channel should be closed after eviction")
+	}
+}
+
+func TestHub_Replay(t *testing.T) {
+	hub := NewHub(HubConfig{BufferSize: 8, History: 3})
+	for _, data := range []string{"a", "b", "c", "d"} {
+		hub.Publish("feed", SSEvent{Data: data})
+	}
+
+	sub, err := hub.SubscribeFrom("2", "feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	var got []string
+	for len(sub.C) > 0 {
+		event := <-sub.C
+		got = append(got, event.Data.(string))
+	}
+	if strings.Join(got, "") != "cd" {
+		t.Errorf("Expected replay of events after ID 2, got %v", got)
+	}
+}
+
+func TestHub_Serve(t *testing.T) {
+	hub := NewHub()
+	engine := New()
+	engine.GET("/events", func(c *Context) {
+		hub.Serve(c, "news")
+	})
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for hub.Subscribers("news") == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	hub.Publish("news", SSEvent{Event: "headline", Data: "hello"})
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	if strings.Join(lines, "|") != "id: 1|event: headline|data: hello" {
+		t.Errorf("Unexpected event lines %v", lines)
+	}
+
+	hub.Close()
+}
+
+func TestHub_ServeClosed(t *testing.T) {
+	hub := NewHub()
+	hub.Close()
+	engine := New()
+	engine.GET("/events", func(c *Context) {
+		if err := hub.Serve(c, "news"); errors.Is(err, ErrHubClosed) {
+			c.Text(http.StatusServiceUnavailable, err.Error())
+		}
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Content-Type") == "text/event-stream" {
+		t.Errorf("Expected 503 from a closed hub, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
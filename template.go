@@ -0,0 +1,290 @@
+package zen
+
+// This file contains HTML template rendering. Templates are loaded once per
+// Engine from a directory or an fs.FS and organised into layouts, partials and
+// pages. Every page is parsed together with all layouts and partials so pages
+// can fill the blocks a layout defines. In DevMode templates are reparsed when
+// a file changes; in Production they are parsed once and cached.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrTemplatesNotLoaded = errors.New("templates have not been loaded")
+	ErrTemplateNotFound   = errors.New("template not found")
+)
+
+// TemplateConfig defines where templates are loaded from and how they are organised
+type TemplateConfig struct {
+	// Dir is the directory templates are loaded from. Ignored when FS is set.
+	Dir string
+
+	// FS is the file system templates are loaded from, for example an embed.FS.
+	FS fs.FS
+
+	// Extension of template files. Default ".html".
+	Extension string
+
+	// LayoutDir is the directory, relative to the root, holding layouts. Default "layouts".
+	LayoutDir string
+
+	// PartialDir is the directory, relative to the root, holding partials. Default "partials".
+	PartialDir string
+
+	// DefaultLayout is the layout used by c.HTML, e.g. "base" for layouts/base.html.
+	// Empty renders pages on their own.
+	DefaultLayout string
+
+	// Funcs are extra functions available in every template.
+	Funcs template.FuncMap
+}
+
+// DefaultTemplateConfig returns the default template configuration
+func DefaultTemplateConfig() TemplateConfig {
+	return TemplateConfig{
+		Dir:        "templates",
+		Extension:  ".html",
+		LayoutDir:  "layouts",
+		PartialDir: "partials",
+	}
+}
+
+// templateRegistry holds the parsed templates of an Engine
+type templateRegistry struct {
+	engine *Engine
+	config TemplateConfig
+	fsys   fs.FS
+
+	mu        sync.RWMutex
+	pages     map[string]*template.Template
+	signature string
+}
+
+// LoadTemplates loads the HTML templates used by Context.HTML.
+//
+// Templates are named by their path relative to the root without the extension.
+// Given the layout
+//
+//	templates/
+//	├── layouts/base.html     {{define "title"}}{{end}} ... {{template "content" .}}
+//	├── partials/nav.html     <nav>...</nav>
+//	└── users/show.html       {{define "content"}}{{template "partials/nav" .}} ...{{end}}
+//
+// a handler renders the user page inside the base layout with
+//
+//	app.LoadTemplates(zen.TemplateConfig{Dir: "templates", DefaultLayout: "base"})
+//	c.HTML(http.StatusOK, "users/show", user)
+//
+// Besides Funcs every template can call url to build the path of a named route:
+//
+//	<a href="{{url "users.show" "id" .ID}}">profile</a>
+func (engine *Engine) LoadTemplates(config TemplateConfig) error {
+	defaults := DefaultTemplateConfig()
+	if config.Extension == "" {
+		config.Extension = defaults.Extension
+	}
+	if config.LayoutDir == "" {
+		config.LayoutDir = defaults.LayoutDir
+	}
+	if config.PartialDir == "" {
+		config.PartialDir = defaults.PartialDir
+	}
+
+	fsys := config.FS
+	if fsys == nil {
+		if config.Dir == "" {
+			config.Dir = defaults.Dir
+		}
+		fsys = os.DirFS(config.Dir)
+	}
+
+	registry := &templateRegistry{engine: engine, config: config, fsys: fsys}
+	if err := registry.load(); err != nil {
+		return err
+	}
+	engine.templates = registry
+	return nil
+}
+
+// HTML renders the named template with the default layout and writes it to the response.
+//
+// Usage:
+//
+//	app.GET("/admin", func(c *zen.Context) {
+//	    c.HTML(http.StatusOK, "admin/dashboard", zen.M{"Users": users})
+//	})
+func (c *Context) HTML(code int, name string, data interface{}) {
+	layout := ""
+	if c.engine != nil && c.engine.templates != nil {
+		layout = c.engine.templates.config.DefaultLayout
+	}
+	c.HTMLWithLayout(code, layout, name, data)
+}
+
+// HTMLWithLayout renders the named template inside the given layout. An empty
+// layout renders the template on its own.
+func (c *Context) HTMLWithLayout(code int, layout, name string, data interface{}) {
+	var buf bytes.Buffer
+	if err := c.renderTemplate(&buf, layout, name, data); err != nil {
+		c.Logger().Error("failed to render template", "template", name, "error", err)
+		c.Text(http.StatusInternalServerError, "500 INTERNAL SERVER ERROR")
+		return
+	}
+
+	c.SetContentType("text/html; charset=utf-8")
+	c.Writer.WriteHeader(code)
+	c.Writer.Write(buf.Bytes())
+}
+
+// renderTemplate executes a page, optionally inside a layout, into buf so a
+// failing template never produces a partial response
+func (c *Context) renderTemplate(buf *bytes.Buffer, layout, name string, data interface{}) error {
+	if c.engine == nil || c.engine.templates == nil {
+		return ErrTemplatesNotLoaded
+	}
+	registry := c.engine.templates
+
+	if c.IsDevMode() {
+		if err := registry.reloadIfChanged(); err != nil {
+			return err
+		}
+	}
+
+	registry.mu.RLock()
+	page, ok := registry.pages[name]
+	registry.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	if layout == "" {
+		return page.ExecuteTemplate(buf, name, data)
+	}
+	return page.ExecuteTemplate(buf, path.Join(registry.config.LayoutDir, layout), data)
+}
+
+// load parses every page together with the layouts and partials
+func (r *templateRegistry) load() error {
+	files, signature, err := r.scan()
+	if err != nil {
+		return err
+	}
+
+	funcs := template.FuncMap{
+		"url": func(name string, params ...interface{}) (string, error) {
+			values := make([]string, len(params))
+			for i, p := range params {
+				values[i] = fmt.Sprint(p)
+			}
+			return r.engine.URL(name, values...)
+		},
+	}
+	for name, fn := range r.config.Funcs {
+		funcs[name] = fn
+	}
+
+	// shared holds the layouts and partials that are parsed into every page
+	shared := template.New("").Funcs(funcs)
+	var pages []string
+	for _, file := range files {
+		name := strings.TrimSuffix(file, r.config.Extension)
+		if r.isShared(name) {
+			if err := r.parse(shared, name, file); err != nil {
+				return err
+			}
+		} else {
+			pages = append(pages, file)
+		}
+	}
+
+	parsed := make(map[string]*template.Template, len(pages))
+	for _, file := range pages {
+		name := strings.TrimSuffix(file, r.config.Extension)
+		page, err := shared.Clone()
+		if err != nil {
+			return err
+		}
+		if err := r.parse(page, name, file); err != nil {
+			return err
+		}
+		parsed[name] = page
+	}
+
+	r.mu.Lock()
+	r.pages = parsed
+	r.signature = signature
+	r.mu.Unlock()
+	return nil
+}
+
+// parse adds the file to set under the given name
+func (r *templateRegistry) parse(set *template.Template, name, file string) error {
+	content, err := fs.ReadFile(r.fsys, file)
+	if err != nil {
+		return err
+	}
+	if _, err := set.New(name).Parse(string(content)); err != nil {
+		return fmt.Errorf("failed to parse template %s: %v", file, err)
+	}
+	return nil
+}
+
+// isShared reports whether a template is a layout or a partial
+func (r *templateRegistry) isShared(name string) bool {
+	return strings.HasPrefix(name, r.config.LayoutDir+"/") || strings.HasPrefix(name, r.config.PartialDir+"/")
+}
+
+// scan lists the template files and computes a signature from their names,
+// sizes and modification times
+func (r *templateRegistry) scan() ([]string, string, error) {
+	var files []string
+	var signature strings.Builder
+
+	err := fs.WalkDir(r.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, r.config.Extension) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, p)
+		fmt.Fprintf(&signature, "%s:%d:%d;", p, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load templates: %v", err)
+	}
+	return files, signature.String(), nil
+}
+
+// reloadIfChanged reparses the templates if any file was added, removed or modified
+func (r *templateRegistry) reloadIfChanged() error {
+	_, signature, err := r.scan()
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	changed := signature != r.signature
+	r.mu.RUnlock()
+
+	if !changed {
+		return nil
+	}
+	r.engine.Logger().Debug("templates changed, reloading")
+	return r.load()
+}
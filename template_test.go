@@ -0,0 +1,119 @@
+package zen
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestEngine_LoadTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":  {Data: []byte(`<title>{{block "title" .}}Zen{{end}}</title><body>{{template "content" .}}</body>`)},
+		"partials/nav.html":  {Data: []byte(`<nav>{{upper .Name}}</nav>`)},
+		"users/show.html":    {Data: []byte(`{{define "title"}}User{{end}}{{define "content"}}{{template "partials/nav" .}}<a href="{{url "users.show" "id" .ID}}">{{.Name}}</a>{{end}}`)},
+		"errors/plain.html":  {Data: []byte(`plain {{.Name}}`)},
+		"users/script.html":  {Data: []byte(`{{define "content"}}<p>{{.Name}}</p>{{end}}`)},
+		"ignored/readme.txt": {Data: []byte(`not a template`)},
+	}
+
+	engine := New()
+	engine.GET("/users/:id", func(c *Context) {}).Name("users.show")
+	err := engine.LoadTemplates(TemplateConfig{
+		FS:            fsys,
+		DefaultLayout: "base",
+		Funcs:         template.FuncMap{"upper": strings.ToUpper},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := M{"ID": 42, "Name": "<zen>"}
+	tests := []struct {
+		name   string
+		render func(c *Context)
+		want   string
+	}{
+		{
+			name:   "page with layout",
+			render: func(c *Context) { c.HTML(http.StatusOK, "users/show", data) },
+			want:   `<title>User</title><body><nav>&lt;ZEN&gt;</nav><a href="/users/42">&lt;zen&gt;</a></body>`,
+		},
+		{
+			name:   "default block",
+			render: func(c *Context) { c.HTML(http.StatusOK, "users/script", data) },
+			want:   `<title>Zen</title><body><p>&lt;zen&gt;</p></body>`,
+		},
+		{
+			name:   "without layout",
+			render: func(c *Context) { c.HTMLWithLayout(http.StatusOK, "", "errors/plain", data) },
+			want:   `plain &lt;zen&gt;`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c := NewContext(w, httptest.NewRequest("GET", "/", nil))
+			c.engine = engine
+			tt.render(c)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			if w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+				t.Errorf("Unexpected Content-Type %q", w.Header().Get("Content-Type"))
+			}
+			if w.Body.String() != tt.want {
+				t.Errorf("Expected body %q, got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	c := NewContext(w, httptest.NewRequest("GET", "/", nil))
+	c.engine = engine
+	c.HTML(http.StatusOK, "missing", nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for a missing template, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestTemplates_DevModeReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(page, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := New()
+	engine.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index", nil) })
+	if err := engine.LoadTemplates(TemplateConfig{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	render := func() string {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w.Body.String()
+	}
+
+	if got := render(); got != "v1" {
+		t.Fatalf("Expected %q, got %q", "v1", got)
+	}
+
+	if err := os.WriteFile(page, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(page, future, future)
+
+	if got := render(); got != "v2" {
+		t.Errorf("Expected template to reload in DevMode, got %q", got)
+	}
+}
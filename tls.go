@@ -0,0 +1,244 @@
+package zen
+
+// This file contains the TLS configuration for serving HTTPS and mutual TLS.
+// Certificates and client CAs are loaded from files and reloaded when the files
+// change, so a rotated certificate is picked up without a restart. The Context
+// helpers expose the identity of a client that presented a verified
+// certificate, for service-to-service authorization.
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTLSReloadInterval is how often certificate files are checked for changes
+const DefaultTLSReloadInterval = 30 * time.Second
+
+var ErrNoClientCAs = errors.New("client certificate verification requires ClientCAFiles")
+
+// TLSOptions configures HTTPS and client certificate verification
+type TLSOptions struct {
+	// CertFile and KeyFile hold the server certificate chain and private key in PEM.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFiles hold the PEM certificates of the CAs client certificates must be signed by.
+	ClientCAFiles []string
+
+	// ClientAuth is the client certificate verification mode. Default
+	// tls.RequireAndVerifyClientCert when ClientCAFiles are set, tls.NoClientCert otherwise.
+	// Use tls.VerifyClientCertIfGiven to serve clients with and without certificates.
+	ClientAuth tls.ClientAuthType
+
+	// MinVersion is the minimum TLS version accepted. Default TLS 1.2.
+	MinVersion uint16
+
+	// ReloadInterval is how often the files are checked for changes, checked
+	// lazily on new connections. Default 30 seconds, negative disables reloading.
+	ReloadInterval time.Duration
+
+	// Logger receives the reload messages, e.g. app.Logger(). Default the
+	// package-level logger.
+	Logger *Log
+}
+
+// LoadTLSConfig loads the certificates of opts and returns a TLS configuration
+// that reloads them when the files change. Set it as ServerConfig.TLSConfig and
+// call ServeTLS without files.
+//
+// Usage:
+//
+//	tlsConfig, err := zen.LoadTLSConfig(zen.TLSOptions{
+//	    CertFile:      "/etc/certs/server.pem",
+//	    KeyFile:       "/etc/certs/server-key.pem",
+//	    ClientCAFiles: []string{"/etc/certs/mesh-ca.pem"},
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	config := zen.DefaultServerConfig()
+//	config.TLSConfig = tlsConfig
+//	app.SetServerConfig(config)
+//	app.ServeTLS(":8443", "", "")
+func LoadTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	if opts.ClientAuth == tls.NoClientCert && len(opts.ClientCAFiles) > 0 {
+		opts.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if opts.ClientAuth >= tls.VerifyClientCertIfGiven && len(opts.ClientCAFiles) == 0 {
+		return nil, ErrNoClientCAs
+	}
+	if opts.MinVersion == 0 {
+		opts.MinVersion = tls.VersionTLS12
+	}
+	if opts.ReloadInterval == 0 {
+		opts.ReloadInterval = DefaultTLSReloadInterval
+	}
+
+	r := &certReloader{opts: opts}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: opts.MinVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}, nil
+}
+
+// certReloader holds the TLS configuration built from the current files
+type certReloader struct {
+	opts TLSOptions
+
+	mu        sync.Mutex
+	config    *tls.Config
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+// current returns the configuration, reloading it first if a file changed.
+// A failed reload keeps the previous configuration so a half-written
+// certificate doesn't take the server down.
+func (r *certReloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.opts.ReloadInterval > 0 && time.Since(r.lastCheck) >= r.opts.ReloadInterval {
+		r.lastCheck = time.Now()
+		if r.changed() {
+			if err := r.loadLocked(); err != nil {
+				r.logger().Error("TLS certificate reload failed, keeping the current certificate", "error", err)
+			} else {
+				r.logger().Info("TLS certificates reloaded")
+			}
+		}
+	}
+	return r.config
+}
+
+// logger returns the logger of the options, or the package-level logger
+func (r *certReloader) logger() *Log {
+	if r.opts.Logger != nil {
+		return r.opts.Logger
+	}
+	return defaultLogger()
+}
+
+func (r *certReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastCheck = time.Now()
+	return r.loadLocked()
+}
+
+func (r *certReloader) loadLocked() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	config := &tls.Config{
+		MinVersion:   r.opts.MinVersion,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.opts.ClientAuth,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if len(r.opts.ClientCAFiles) > 0 {
+		config.ClientCAs = x509.NewCertPool()
+		for _, file := range r.opts.ClientCAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if !config.ClientCAs.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificates found in %s", file)
+			}
+		}
+	}
+
+	r.config = config
+	r.modTimes = modTimes
+	return nil
+}
+
+// changed reports whether any file was modified since it was loaded
+func (r *certReloader) changed() bool {
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *certReloader) files() []string {
+	return append([]string{r.opts.CertFile, r.opts.KeyFile}, r.opts.ClientCAFiles...)
+}
+
+// PeerCertificate returns the client certificate verified during the TLS
+// handshake, or nil if the client presented none or the connection is not TLS.
+func (c *Context) PeerCertificate() *x509.Certificate {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 || len(c.Request.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return c.Request.TLS.VerifiedChains[0][0]
+}
+
+// PeerSubject returns the subject of the verified client certificate, e.g.
+// "CN=payments,O=Example", or "" without one.
+func (c *Context) PeerSubject() string {
+	cert := c.PeerCertificate()
+	if cert == nil {
+		return ""
+	}
+	return cert.Subject.String()
+}
+
+// PeerSANs returns the subject alternative names of the verified client
+// certificate: DNS names, IP addresses, URIs and email addresses.
+func (c *Context) PeerSANs() []string {
+	cert := c.PeerCertificate()
+	if cert == nil {
+		return nil
+	}
+	sans := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return append(sans, cert.EmailAddresses...)
+}
+
+// PeerSPIFFEID returns the SPIFFE ID of the verified client certificate, e.g.
+// "spiffe://example.org/ns/prod/sa/payments", or "" if it has none. Per the
+// SPIFFE X.509 SVID spec a certificate with several URI SANs has no SPIFFE ID.
+func (c *Context) PeerSPIFFEID() string {
+	cert := c.PeerCertificate()
+	if cert == nil || len(cert.URIs) != 1 {
+		return ""
+	}
+	uri := cert.URIs[0]
+	if !strings.EqualFold(uri.Scheme, "spiffe") || uri.Host == "" {
+		return ""
+	}
+	return uri.String()
+}
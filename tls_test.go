@@ -0,0 +1,213 @@
+package zen
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and key signed by a test CA
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key}
+}
+
+func (tc *testCert) writePEM(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tc.cert.Raw}), 0600)
+	if keyFile != "" {
+		der, _ := x509.MarshalECPrivateKey(tc.key)
+		os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+	}
+}
+
+func (tc *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{tc.cert.Raw}, PrivateKey: tc.key}
+}
+
+func newTestCA(t *testing.T, name string) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+}
+
+func newTestServerCert(t *testing.T, ca *testCert, name string) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+}
+
+func TestEngine_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server-key.pem")
+	caFile := filepath.Join(dir, "client-ca.pem")
+
+	serverCA := newTestCA(t, "server CA")
+	clientCA := newTestCA(t, "client CA")
+	clientCA.writePEM(t, caFile, "")
+	newTestServerCert(t, serverCA, "server-1").writePEM(t, certFile, keyFile)
+
+	spiffeID, _ := url.Parse("spiffe://example.org/ns/prod/sa/billing")
+	client := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "billing", Organization: []string{"Example"}},
+		DNSNames:    []string{"billing.internal"},
+		URIs:        []*url.URL{spiffeID},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, clientCA)
+	untrusted := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "intruder"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, newTestCA(t, "other CA"))
+
+	tlsConfig, err := LoadTLSConfig(TLSOptions{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ClientCAFiles:  []string{caFile},
+		ReloadInterval: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := New()
+	engine.GET("/whoami", func(c *Context) {
+		c.JSON(http.StatusOK, M{
+			"subject": c.PeerSubject(),
+			"sans":    c.PeerSANs(),
+			"spiffe":  c.PeerSPIFFEID(),
+		})
+	})
+	config := DefaultServerConfig()
+	config.TLSConfig = tlsConfig
+	engine.SetServerConfig(config)
+
+	started := make(chan string, 1)
+	engine.OnStart(func(context.Context) error {
+		started <- engine.addr
+		return nil
+	})
+	go engine.ServeTLS("127.0.0.1:0", "", "")
+	defer engine.Shutdown(time.Second)
+	addr := <-started
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	get := func(cert *testCert) (*http.Response, string, error) {
+		clientConfig := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			clientConfig.Certificates = []tls.Certificate{cert.tlsCertificate()}
+		}
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+		resp, err := httpClient.Get("https://" + addr + "/whoami")
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body), nil
+	}
+
+	resp, body, err := get(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"sans":["billing.internal","spiffe://example.org/ns/prod/sa/billing"],"spiffe":"spiffe://example.org/ns/prod/sa/billing","subject":"CN=billing,O=Example"}`
+	if body != want+"\n" && body != want {
+		t.Errorf("Expected identity %s, got %s", want, body)
+	}
+	if name := resp.TLS.PeerCertificates[0].Subject.CommonName; name != "server-1" {
+		t.Errorf("Expected server certificate server-1, got %s", name)
+	}
+
+	if _, _, err := get(nil); err == nil {
+		t.Error("Expected a client without a certificate to be rejected")
+	}
+	if _, _, err := get(untrusted); err == nil {
+		t.Error("Expected a certificate from an unknown CA to be rejected")
+	}
+
+	// A rotated certificate is served without a restart
+	newTestServerCert(t, serverCA, "server-2").writePEM(t, certFile, keyFile)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	resp, _, err = get(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := resp.TLS.PeerCertificates[0].Subject.CommonName; name != "server-2" {
+		t.Errorf("Expected the reloaded certificate server-2, got %s", name)
+	}
+}
+
+func TestLoadTLSConfig_VerificationModes(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server-key.pem")
+	newTestServerCert(t, newTestCA(t, "CA"), "server").writePEM(t, certFile, keyFile)
+
+	if _, err := LoadTLSConfig(TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientAuth: tls.RequireAndVerifyClientCert}); err != ErrNoClientCAs {
+		t.Errorf("Expected %v, got %v", ErrNoClientCAs, err)
+	}
+
+	caFile := filepath.Join(dir, "ca.pem")
+	newTestCA(t, "client CA").writePEM(t, caFile, "")
+	config, err := LoadTLSConfig(TLSOptions{
+		CertFile:      certFile,
+		KeyFile:       keyFile,
+		ClientCAFiles: []string{caFile},
+		ClientAuth:    tls.VerifyClientCertIfGiven,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _ := config.GetConfigForClient(nil)
+	if current.ClientAuth != tls.VerifyClientCertIfGiven || current.ClientCAs == nil {
+		t.Errorf("Expected optional verification against the client CAs, got %v", current.ClientAuth)
+	}
+
+	if _, err := LoadTLSConfig(TLSOptions{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+}
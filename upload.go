@@ -0,0 +1,650 @@
+package zen
+
+// This file contains multipart upload handling. Multipart bodies are parsed as a
+// stream: file parts are written to temporary files as they arrive so large
+// uploads never sit in memory, and every file is checked against the per-file
+// and total size limits and the allowed file types before it is accepted. File
+// types are checked by extension and by sniffing the first bytes of the content,
+// so a renamed executable is rejected. Temporary files are removed when the
+// request ends.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrNotMultipart       = errors.New("request is not multipart/form-data")
+	ErrFileTooLarge       = errors.New("uploaded file too large")
+	ErrUploadTooLarge     = errors.New("upload too large")
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+)
+
+// sniffLen is the number of bytes used to detect the content type of a file
+const sniffLen = 512
+
+// UploadConfig holds the limits applied when parsing multipart uploads
+type UploadConfig struct {
+	// MaxFileSize is the maximum size of a single file in bytes. Default 10MB.
+	MaxFileSize int64
+
+	// MaxTotalSize is the maximum size of all parts together in bytes. Default 32MB.
+	// The body limit of the route applies as well.
+	MaxTotalSize int64
+
+	// MaxMemory is the maximum size of all non-file form values in bytes. Default 1MB.
+	MaxMemory int64
+
+	// AllowedTypes lists the allowed file extensions, e.g. ".png". Files must
+	// also have content matching the extension. Empty allows any file.
+	AllowedTypes []string
+
+	// TempDir is where uploaded files are stored until the request ends.
+	// Default os.TempDir().
+	TempDir string
+
+	// OnProgress is called as file data is read.
+	OnProgress func(UploadProgress)
+}
+
+// UploadProgress reports how much of an upload has been read
+type UploadProgress struct {
+	Field         string
+	Filename      string
+	FileBytes     int64 // bytes read of the current file
+	TotalBytes    int64 // bytes read of all parts
+	ContentLength int64 // the request Content-Length, -1 if unknown
+}
+
+// DefaultUploadConfig returns the default upload configuration
+func DefaultUploadConfig() UploadConfig {
+	return UploadConfig{
+		MaxFileSize:  10 << 20, // 10MB
+		MaxTotalSize: 32 << 20, // 32MB
+		MaxMemory:    1 << 20,  // 1MB
+	}
+}
+
+// UploadedFile is a file of a multipart upload stored in a temporary file
+type UploadedFile struct {
+	Field       string
+	Filename    string // the base name sent by the client, not safe to use as a path as is
+	Header      textproto.MIMEHeader
+	Size        int64
+	ContentType string // detected from the content
+	path        string
+}
+
+// Open opens the uploaded file for reading.
+func (f *UploadedFile) Open() (*os.File, error) {
+	return os.Open(f.path)
+}
+
+// TempPath returns the path of the temporary file, which is removed when the request ends.
+func (f *UploadedFile) TempPath() string {
+	return f.path
+}
+
+// UploadForm is a parsed multipart form
+type UploadForm struct {
+	Value map[string][]string
+	File  map[string][]*UploadedFile
+}
+
+// UploadPart is a part of a multipart body being streamed. Reading it enforces
+// the upload limits and reports progress.
+type UploadPart struct {
+	Field       string
+	Filename    string // empty for form values
+	Header      textproto.MIMEHeader
+	ContentType string // detected from the content, empty for form values
+
+	c      *Context
+	r      io.Reader
+	size   int64
+	total  *int64
+	config *UploadConfig
+}
+
+// Read reads the part, returning ErrFileTooLarge or ErrUploadTooLarge once a limit is exceeded
+func (p *UploadPart) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.size += int64(n)
+	*p.total += int64(n)
+
+	if p.Filename != "" && p.size > p.config.MaxFileSize {
+		return n, fmt.Errorf("%w: %s", ErrFileTooLarge, p.Filename)
+	}
+	if *p.total > p.config.MaxTotalSize {
+		return n, ErrUploadTooLarge
+	}
+	if n > 0 && p.Filename != "" && p.config.OnProgress != nil {
+		p.config.OnProgress(UploadProgress{
+			Field:         p.Field,
+			Filename:      p.Filename,
+			FileBytes:     p.size,
+			TotalBytes:    *p.total,
+			ContentLength: p.c.Request.ContentLength,
+		})
+	}
+	return n, err
+}
+
+// SetUploadConfig sets the upload limits for every route. Zero fields take
+// their defaults.
+//
+// Usage:
+//
+//	app.SetUploadConfig(zen.UploadConfig{
+//	    MaxFileSize:  5 << 20,
+//	    AllowedTypes: []string{".png", ".jpg", ".pdf"},
+//	})
+func (engine *Engine) SetUploadConfig(config UploadConfig) {
+	config = normalizeUploadConfig(config)
+	engine.uploadConfig = &config
+}
+
+// Uploads returns a middleware that overrides the upload limits for the routes
+// it is applied to.
+//
+// Usage:
+//
+//	media := app.GroupRoutes("/media")
+//	media.Apply(zen.Uploads(zen.UploadConfig{MaxFileSize: 100 << 20}))
+func Uploads(config UploadConfig) HandlerFunc {
+	config = normalizeUploadConfig(config)
+	return func(c *Context) {
+		cfg := config
+		c.upload = &cfg
+		c.Next()
+	}
+}
+
+// SetAllowedFileTypes restricts the file extensions accepted for this request,
+// on top of UploadConfig.AllowedTypes. Used by middleware enforcing an upload
+// policy: files are checked when the body is parsed, with the limits of the
+// Uploads middleware applied after it. The check also applies when the body is
+// parsed by c.Request.FormFile or c.Request.ParseMultipartForm, which then
+// fail with ErrFileTypeNotAllowed.
+func (c *Context) SetAllowedFileTypes(types ...string) {
+	c.allowedTypes = types
+	if _, checked := c.Request.Body.(*checkedUploadBody); checked || c.Request.Body == nil {
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(c.GetContentType()); mediaType == "multipart/form-data" {
+		c.Request.Body = &checkedUploadBody{c: c, rc: c.Request.Body}
+	}
+}
+
+// checkedUploadBody is a multipart body that is parsed with MultipartForm, and
+// so checked, when something else reads it, e.g. c.Request.FormFile. The
+// stored form is then read in its place.
+type checkedUploadBody struct {
+	c  *Context
+	rc io.ReadCloser
+	r  io.Reader
+}
+
+func (b *checkedUploadBody) Read(p []byte) (int, error) {
+	if b.r == nil {
+		if _, err := b.c.MultipartForm(); err != nil {
+			b.r = errorReader{err}
+		} else {
+			b.r = b.c.Request.Body
+		}
+	}
+	return b.r.Read(p)
+}
+
+func (b *checkedUploadBody) Close() error {
+	return b.rc.Close()
+}
+
+// errorReader fails every read with err
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// MultipartForm parses a multipart/form-data body, storing files in temporary
+// files. The form, or the error, is cached, so it can be called from middleware
+// and handlers. Afterwards c.Request.FormValue and c.Request.FormFile read the
+// parsed form as well.
+//
+// Usage:
+//
+//	form, err := c.MultipartForm()
+//	if err != nil {
+//	    c.Error(http.StatusBadRequest, err.Error())
+//	    return
+//	}
+//	for _, file := range form.File["photos"] {
+//	    c.SaveUploadedFile(file, filepath.Join("uploads", uuid.NewString()+filepath.Ext(file.Filename)))
+//	}
+func (c *Context) MultipartForm() (*UploadForm, error) {
+	if c.uploadForm != nil || c.uploadErr != nil {
+		return c.uploadForm, c.uploadErr
+	}
+
+	config := c.uploadConfig()
+	form := &UploadForm{
+		Value: make(map[string][]string),
+		File:  make(map[string][]*UploadedFile),
+	}
+
+	var memory int64
+	err := c.StreamMultipart(func(part *UploadPart) error {
+		if part.Filename == "" {
+			var buf bytes.Buffer
+			n, err := io.Copy(&buf, io.LimitReader(part, config.MaxMemory-memory+1))
+			if err != nil {
+				return err
+			}
+			memory += n
+			if memory > config.MaxMemory {
+				return ErrUploadTooLarge
+			}
+			form.Value[part.Field] = append(form.Value[part.Field], buf.String())
+			return nil
+		}
+
+		file, err := c.storeUpload(part)
+		if err != nil {
+			return err
+		}
+		form.File[part.Field] = append(form.File[part.Field], file)
+		return nil
+	})
+	if err != nil {
+		c.uploadErr = err
+		return nil, err
+	}
+
+	// Make the values available through c.Request.FormValue as well
+	if c.Request.Form == nil {
+		c.Request.ParseForm()
+	}
+	if c.Request.PostForm == nil {
+		c.Request.PostForm = make(map[string][]string)
+	}
+	for key, values := range form.Value {
+		c.Request.Form[key] = append(c.Request.Form[key], values...)
+		c.Request.PostForm[key] = append(c.Request.PostForm[key], values...)
+	}
+	c.replayUploads(form)
+
+	c.uploadForm = form
+	return form, nil
+}
+
+// FormFile returns the first file uploaded under the given field name.
+// http.ErrMissingFile is returned if there is none.
+//
+// Usage:
+//
+//	file, err := c.FormFile("avatar")
+//	if errors.Is(err, zen.ErrFileTypeNotAllowed) {
+//	    c.Error(http.StatusUnsupportedMediaType, err.Error())
+//	    return
+//	}
+func (c *Context) FormFile(name string) (*UploadedFile, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+// SaveUploadedFile copies an uploaded file to dst, creating its directory if needed.
+//
+// Usage:
+//
+//	file, err := c.FormFile("avatar")
+//	if err != nil { ... }
+//	c.SaveUploadedFile(file, filepath.Join("avatars", userID+filepath.Ext(file.Filename)))
+func (c *Context) SaveUploadedFile(file *UploadedFile, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// StreamMultipart calls fn for every part of a multipart/form-data body as it
+// is read, without storing anything. File parts are checked against the allowed
+// types before fn is called and fail with ErrFileTooLarge or ErrUploadTooLarge
+// when read past the limits.
+//
+// Usage:
+//
+//	err := c.StreamMultipart(func(part *zen.UploadPart) error {
+//	    if part.Filename == "" {
+//	        return nil
+//	    }
+//	    return bucket.Put(part.Filename, part)
+//	})
+func (c *Context) StreamMultipart(fn func(part *UploadPart) error) error {
+	config := c.uploadConfig()
+	if body, ok := c.Request.Body.(*checkedUploadBody); ok {
+		// The parts are checked here, read the body itself
+		c.Request.Body = body.rc
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		if errors.Is(err, http.ErrNotMultipart) {
+			return ErrNotMultipart
+		}
+		return err
+	}
+
+	var total int64
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		part := &UploadPart{
+			Field:    p.FormName(),
+			Filename: p.FileName(),
+			Header:   p.Header,
+			c:        c,
+			r:        p,
+			total:    &total,
+			config:   config,
+		}
+
+		if part.Filename != "" {
+			// Read the start of the file to check its content
+			head := make([]byte, sniffLen)
+			n, err := io.ReadFull(p, head)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				p.Close()
+				return err
+			}
+			head = head[:n]
+
+			part.ContentType, err = checkFileType(part.Filename, head, config.AllowedTypes)
+			if err == nil && len(c.allowedTypes) > 0 {
+				_, err = checkFileType(part.Filename, head, c.allowedTypes)
+			}
+			if err != nil {
+				p.Close()
+				return err
+			}
+			part.r = io.MultiReader(bytes.NewReader(head), p)
+		}
+
+		err = fn(part)
+		p.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// storeUpload writes a file part to a temporary file that is removed when the request ends
+func (c *Context) storeUpload(part *UploadPart) (*UploadedFile, error) {
+	tmp, err := os.CreateTemp(c.uploadConfig().TempDir, "zen-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	path := tmp.Name()
+	c.onCleanup(func() { os.Remove(path) })
+
+	size, err := io.Copy(tmp, part)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadedFile{
+		Field:       part.Field,
+		Filename:    part.Filename,
+		Header:      part.Header,
+		Size:        size,
+		ContentType: part.ContentType,
+		path:        path,
+	}, nil
+}
+
+// replayUploads makes the form readable by c.Request.ParseMultipartForm, and
+// so c.Request.FormFile: the request body, consumed by MultipartForm, is
+// replaced by the values and stored files encoded again, which is only done if
+// the request's own parsing is used. The encoding stops before the files are
+// removed at the end of the request.
+func (c *Context) replayUploads(form *UploadForm) {
+	_, params, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	replay := &uploadReplay{c: c, form: form, boundary: params["boundary"]}
+	c.Request.MultipartForm = nil
+	c.Request.Body = replay
+	c.onCleanup(func() { replay.Close() })
+}
+
+// uploadReplay is a multipart body of a stored form, encoded when first read
+type uploadReplay struct {
+	c        *Context
+	form     *UploadForm
+	boundary string
+	r        *io.PipeReader
+	done     chan struct{} // closed when the encoding has stopped
+}
+
+func (u *uploadReplay) Read(p []byte) (int, error) {
+	if u.r == nil {
+		// The values are read again with the body, so hand them back to the
+		// request's parser rather than have them twice
+		u.c.Request.Form = u.c.Request.URL.Query()
+		u.c.Request.PostForm = nil
+
+		var w *io.PipeWriter
+		u.r, w = io.Pipe()
+		u.done = make(chan struct{})
+		go func() {
+			defer close(u.done)
+			w.CloseWithError(u.write(w))
+		}()
+	}
+	return u.r.Read(p)
+}
+
+func (u *uploadReplay) write(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(u.boundary); err != nil {
+		return err
+	}
+	for _, field := range slices.Sorted(maps.Keys(u.form.Value)) {
+		for _, value := range u.form.Value[field] {
+			if err := mw.WriteField(field, value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, field := range slices.Sorted(maps.Keys(u.form.File)) {
+		if err := u.writeFiles(mw, u.form.File[field]); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// writeFiles encodes stored files as parts of mw
+func (u *uploadReplay) writeFiles(mw *multipart.Writer, files []*UploadedFile) error {
+	for _, file := range files {
+		part, err := mw.CreatePart(file.Header)
+		if err != nil {
+			return err
+		}
+		src, err := file.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(part, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the encoding and waits for it, so the stored files are no longer read
+func (u *uploadReplay) Close() error {
+	if u.r == nil {
+		return nil
+	}
+	err := u.r.Close()
+	<-u.done
+	return err
+}
+
+// uploadConfig returns the upload limits for this request
+func (c *Context) uploadConfig() *UploadConfig {
+	if c.upload == nil {
+		config := DefaultUploadConfig()
+		if c.engine != nil && c.engine.uploadConfig != nil {
+			config = *c.engine.uploadConfig
+		}
+		c.upload = &config
+	}
+	return c.upload
+}
+
+// normalizeUploadConfig fills zero fields with their defaults
+func normalizeUploadConfig(config UploadConfig) UploadConfig {
+	defaults := DefaultUploadConfig()
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = defaults.MaxFileSize
+	}
+	if config.MaxTotalSize <= 0 {
+		config.MaxTotalSize = defaults.MaxTotalSize
+	}
+	if config.MaxMemory <= 0 {
+		config.MaxMemory = defaults.MaxMemory
+	}
+	return config
+}
+
+// oleType is reported for legacy Office documents, which http.DetectContentType does not know
+const oleType = "application/x-ole-storage"
+
+var oleMagic = []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1")
+
+// fileSignatures maps file extensions to the detected content types their
+// content may have. Extensions not listed are only checked by name.
+var fileSignatures = map[string][]string{
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".png":  {"image/png"},
+	".gif":  {"image/gif"},
+	".webp": {"image/webp"},
+	".bmp":  {"image/bmp"},
+	".ico":  {"image/x-icon"},
+	".pdf":  {"application/pdf"},
+	".zip":  {"application/zip"},
+	".gz":   {"application/x-gzip"},
+	".docx": {"application/zip"},
+	".xlsx": {"application/zip"},
+	".pptx": {"application/zip"},
+	".doc":  {oleType},
+	".xls":  {oleType},
+	".ppt":  {oleType},
+	".mp3":  {"audio/mpeg"},
+	".mp4":  {"video/mp4"},
+	".webm": {"video/webm"},
+	".wav":  {"audio/wave"},
+	".ogg":  {"application/ogg", "audio/ogg", "video/ogg"},
+	".txt":  {"text/plain"},
+	".csv":  {"text/plain"},
+	".json": {"text/plain"},
+}
+
+// checkFileType detects the content type of a file from its first bytes and,
+// if allowed is not empty, checks both the extension and the content against it
+func checkFileType(filename string, head []byte, allowed []string) (string, error) {
+	contentType := http.DetectContentType(head)
+	if bytes.HasPrefix(head, oleMagic) {
+		contentType = oleType
+	}
+	if len(allowed) == 0 {
+		return contentType, nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	permitted := false
+	for _, t := range allowed {
+		t = strings.ToLower(t)
+		if !strings.HasPrefix(t, ".") {
+			t = "." + t
+		}
+		if ext == t {
+			permitted = true
+			break
+		}
+	}
+	if !permitted {
+		return "", fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, filename)
+	}
+
+	signatures, known := fileSignatures[ext]
+	if !known {
+		return contentType, nil
+	}
+	for _, signature := range signatures {
+		if strings.HasPrefix(contentType, signature) {
+			return contentType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s content does not match its extension", ErrFileTypeNotAllowed, filename)
+}
+
+// onCleanup registers fn to run when the request ends
+func (c *Context) onCleanup(fn func()) {
+	c.cleanups = append(c.cleanups, fn)
+}
+
+// cleanup runs the functions registered with onCleanup
+func (c *Context) cleanup() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+	c.cleanups = nil
+}
@@ -0,0 +1,224 @@
+package zen
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+// multipartRequest builds a multipart request from field values and files given as name => content
+func multipartRequest(t *testing.T, values map[string]string, files map[string]string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for field, value := range values {
+		w.WriteField(field, value)
+	}
+	for filename, content := range files {
+		part, err := w.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+	w.Close()
+
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestContext_FormFile(t *testing.T) {
+	engine := New()
+	dir := t.TempDir()
+
+	var tempPath string
+	var progress []UploadProgress
+	engine.SetUploadConfig(UploadConfig{
+		OnProgress: func(p UploadProgress) { progress = append(progress, p) },
+	})
+	engine.POST("/upload", func(c *Context) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.Text(http.StatusBadRequest, err.Error())
+			return
+		}
+		tempPath = file.TempPath()
+
+		if err := c.SaveUploadedFile(file, filepath.Join(dir, "avatars", file.Filename)); err != nil {
+			c.Text(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Text(http.StatusOK, "%s %s %d %s", c.Request.FormValue("name"), file.Filename, file.Size, file.ContentType)
+	})
+
+	content := pngHeader + strings.Repeat("x", 100)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, multipartRequest(t, map[string]string{"name": "John"}, map[string]string{"avatar.png": content}))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if expected := "John avatar.png 116 image/png"; w.Body.String() != expected {
+		t.Errorf("Expected body %q, got %q", expected, w.Body.String())
+	}
+
+	saved, err := os.ReadFile(filepath.Join(dir, "avatars", "avatar.png"))
+	if err != nil || string(saved) != content {
+		t.Errorf("Expected saved file to match the upload, got %v", err)
+	}
+	if _, err := os.Stat(tempPath); !os.IsNotExist(err) {
+		t.Error("Temporary file should be removed when the request ends")
+	}
+	if len(progress) == 0 || progress[len(progress)-1].FileBytes != int64(len(content)) {
+		t.Errorf("Expected progress up to %d bytes, got %+v", len(content), progress)
+	}
+}
+
+func TestContext_MultipartFormLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    UploadConfig
+		bodyLimit int64
+		files     map[string]string
+		wantErr   error
+	}{
+		{
+			name:   "allowed type",
+			config: UploadConfig{AllowedTypes: []string{".png", "pdf"}},
+			files:  map[string]string{"doc.PDF": "%PDF-1.7\n..."},
+		},
+		{
+			name:    "extension not allowed",
+			config:  UploadConfig{AllowedTypes: []string{".png"}},
+			files:   map[string]string{"run.exe": "MZ\x90\x00"},
+			wantErr: ErrFileTypeNotAllowed,
+		},
+		{
+			name:    "content does not match extension",
+			config:  UploadConfig{AllowedTypes: []string{".png"}},
+			files:   map[string]string{"image.png": "MZ\x90\x00 not really a png"},
+			wantErr: ErrFileTypeNotAllowed,
+		},
+		{
+			name:    "file too large",
+			config:  UploadConfig{MaxFileSize: 10},
+			files:   map[string]string{"big.txt": strings.Repeat("a", 11)},
+			wantErr: ErrFileTooLarge,
+		},
+		{
+			name:    "total too large",
+			config:  UploadConfig{MaxFileSize: 10, MaxTotalSize: 15},
+			files:   map[string]string{"a.txt": strings.Repeat("a", 8), "b.txt": strings.Repeat("b", 8)},
+			wantErr: ErrUploadTooLarge,
+		},
+		{
+			name:      "body limit",
+			bodyLimit: 100,
+			files:     map[string]string{"big.txt": strings.Repeat("a", 200)},
+			wantErr:   ErrBodyTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := multipartRequest(t, nil, tt.files)
+			c := NewContext(httptest.NewRecorder(), req)
+			defer c.cleanup()
+			c.bodyLimit = tt.bodyLimit
+			req.Body = &limitedBody{c: c, rc: req.Body}
+			config := normalizeUploadConfig(tt.config)
+			c.upload = &config
+
+			_, err := c.MultipartForm()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestContext_MultipartFormRequestAccess(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), multipartRequest(t, map[string]string{"title": "hi"}, map[string]string{"a.png": pngHeader}))
+	defer c.cleanup()
+	if _, err := c.MultipartForm(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var content bytes.Buffer
+	content.ReadFrom(file)
+	if header.Filename != "a.png" || content.String() != pngHeader {
+		t.Errorf("Expected the uploaded file through the request, got %q with %q", header.Filename, content.String())
+	}
+	if c.Request.FormValue("title") != "hi" || len(c.Request.Form["title"]) != 1 {
+		t.Errorf("Expected the value once through the request, got %q", c.Request.Form["title"])
+	}
+	if values := c.Request.MultipartForm.Value["title"]; len(values) != 1 || values[0] != "hi" {
+		t.Errorf("Expected the value in the request's multipart form, got %q", values)
+	}
+
+	// A body read in part stops being encoded before the files are removed
+	c = NewContext(httptest.NewRecorder(), multipartRequest(t, nil, map[string]string{"a.png": pngHeader + strings.Repeat("x", 1<<16)}))
+	form, err := c.MultipartForm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := c.Request.Body.(*uploadReplay)
+	c.Request.Body.Read(make([]byte, 10))
+	c.cleanup()
+	select {
+	case <-replay.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the replayed body to stop when the request ends")
+	}
+	if _, err := os.Stat(form.File["file"][0].TempPath()); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be removed, got %v", err)
+	}
+
+	// A failed parse is cached too, the body is gone
+	c = NewContext(httptest.NewRecorder(), multipartRequest(t, nil, map[string]string{"a.exe": "MZ"}))
+	c.SetAllowedFileTypes(".png")
+	for i := 0; i < 2; i++ {
+		if _, err := c.MultipartForm(); !errors.Is(err, ErrFileTypeNotAllowed) {
+			t.Errorf("Call %d: expected %v, got %v", i+1, ErrFileTypeNotAllowed, err)
+		}
+	}
+}
+
+func TestContext_StreamMultipart(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), multipartRequest(t, map[string]string{"title": "hi"}, map[string]string{"a.png": pngHeader}))
+
+	var parts []string
+	err := c.StreamMultipart(func(part *UploadPart) error {
+		var buf bytes.Buffer
+		buf.ReadFrom(part)
+		parts = append(parts, part.Field+"="+part.ContentType+":"+buf.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 || parts[0] != "title=:hi" || parts[1] != "file=image/png:"+pngHeader {
+		t.Errorf("Unexpected parts %q", parts)
+	}
+
+	c = NewContext(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader("{}")))
+	if err := c.StreamMultipart(func(*UploadPart) error { return nil }); err != ErrNotMultipart {
+		t.Errorf("Expected %v, got %v", ErrNotMultipart, err)
+	}
+}
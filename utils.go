@@ -28,7 +28,7 @@ func (engine *Engine) zenAsciiArt(port string) string {
     ╚══════╝╚══════╝╚═╝  ╚═══╝%s
     
     %s🎋 Lightweight, Secure & Fast HTTP Framework for Modern Apps%s
-    %s⚡ Running on port %s%s
+    %s⚡ Running on %s%s
     %s✨ %s%s
     `,
 		Cyan, Reset,
@@ -0,0 +1,107 @@
+package websocket
+
+// This file implements the permessage-deflate extension (RFC 7692). Both sides
+// are asked to reset the compression context after every message, which keeps
+// memory per connection low and lets compress/flate be used directly.
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+	"sync"
+)
+
+// deflateTail is the empty stored block that ends a flushed deflate stream.
+// It is removed before sending and restored before inflating (RFC 7692 section 7.2).
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+// deflateFinal marks the end of the stream so the reader reports io.EOF
+var deflateFinal = []byte{0x01, 0x00, 0x00, 0xff, 0xff}
+
+// permessageDeflate is the extension name sent in Sec-WebSocket-Extensions
+const permessageDeflate = "permessage-deflate"
+
+var flateWriters [flate.BestCompression - flate.HuffmanOnly + 1]sync.Pool
+
+// compressMessage compresses a message payload
+func compressMessage(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	pool := &flateWriters[level-flate.HuffmanOnly]
+	fw, _ := pool.Get().(*flate.Writer)
+	if fw == nil {
+		var err error
+		if fw, err = flate.NewWriter(&buf, level); err != nil {
+			return nil, err
+		}
+	} else {
+		fw.Reset(&buf)
+	}
+	defer pool.Put(fw)
+
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail), nil
+}
+
+// decompressMessage inflates a message payload, failing with ErrReadLimit if
+// the result is larger than limit. A limit of zero or less means no limit.
+func decompressMessage(data []byte, limit int64) ([]byte, error) {
+	fr := flate.NewReader(io.MultiReader(
+		bytes.NewReader(data),
+		bytes.NewReader(deflateTail),
+		bytes.NewReader(deflateFinal),
+	))
+	defer fr.Close()
+
+	var reader io.Reader = fr
+	if limit > 0 {
+		reader = io.LimitReader(fr, limit+1)
+	}
+
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, &protocolError{CloseInvalidFramePayloadData, "invalid compressed data"}
+	}
+	if limit > 0 && int64(len(out)) > limit {
+		return nil, ErrReadLimit
+	}
+	return out, nil
+}
+
+// negotiateDeflate returns the Sec-WebSocket-Extensions response value if the
+// client offered permessage-deflate with parameters the server can honour
+func negotiateDeflate(header string) (string, bool) {
+	for _, offer := range strings.Split(header, ",") {
+		params := strings.Split(offer, ";")
+		if strings.TrimSpace(params[0]) != permessageDeflate {
+			continue
+		}
+
+		acceptable := true
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			switch strings.TrimSpace(name) {
+			case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+			case "server_max_window_bits":
+				// compress/flate always uses a 32KB window
+				if strings.Trim(strings.TrimSpace(value), `"`) != "15" {
+					acceptable = false
+				}
+			default:
+				acceptable = false
+			}
+		}
+
+		if acceptable {
+			return permessageDeflate + "; server_no_context_takeover; client_no_context_takeover", true
+		}
+	}
+	return "", false
+}
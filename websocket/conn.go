@@ -0,0 +1,582 @@
+// Package websocket implements the WebSocket protocol (RFC 6455) for the Zen framework,
+// including the permessage-deflate extension (RFC 7692) and a rooms/broadcast hub.
+//
+// The entry point is websocket.Handler rather than a zen.WebSocket method: the
+// package builds on zen.Context, so zen can't import it without an import cycle.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ThembinkosiThemba/zen"
+)
+
+// Message types, as defined in RFC 6455 section 11.8.
+const (
+	TextMessage   = 1  // TextMessage denotes a UTF-8 encoded text message.
+	BinaryMessage = 2  // BinaryMessage denotes a binary data message.
+	CloseMessage  = 8  // CloseMessage denotes a close control message.
+	PingMessage   = 9  // PingMessage denotes a ping control message.
+	PongMessage   = 10 // PongMessage denotes a pong control message.
+
+	continuationFrame = 0
+)
+
+// Close codes, as defined in RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseTryAgainLater           = 1013
+)
+
+// maxControlPayload is the largest payload allowed in a control frame
+const maxControlPayload = 125
+
+// maxFramePayload is the largest frame read when the read limit is disabled, as
+// the frame is allocated at the length the peer declares
+const maxFramePayload = 64 << 20
+
+// custom errors
+var (
+	ErrClosed        = errors.New("websocket: connection closed")
+	ErrReadLimit     = errors.New("websocket: read limit exceeded")
+	ErrInvalidType   = errors.New("websocket: invalid message type")
+	ErrControlTooBig = errors.New("websocket: control frame payload exceeds 125 bytes")
+)
+
+// CloseError is returned by ReadMessage when the peer closes the connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// protocolError is a violation of the protocol by the peer. The connection is
+// closed with the given code.
+type protocolError struct {
+	code int
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return "websocket: " + e.msg
+}
+
+// IsCloseError reports whether err is a *CloseError with one of the given codes.
+func IsCloseError(err error, codes ...int) bool {
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		return false
+	}
+	for _, code := range codes {
+		if closeErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn is a WebSocket connection.
+//
+// Applications may call ReadMessage from one goroutine and any of the write
+// methods from any number of goroutines concurrently.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+	ctx      *zen.Context
+
+	subprotocol      string
+	compress         bool // permessage-deflate was negotiated
+	writeCompression bool // compress outgoing data messages
+	compressionLevel int
+
+	readLimit   int64
+	readErr     error
+	pingHandler func(appData string) error
+	pongHandler func(appData string) error
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+// newConn creates a Conn over an established connection
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{
+		conn:     conn,
+		br:       br,
+		isServer: isServer,
+	}
+	c.pingHandler = func(appData string) error {
+		err := c.WriteControl(PongMessage, []byte(appData), time.Now().Add(time.Second))
+		if errors.Is(err, ErrClosed) {
+			return nil
+		}
+		return err
+	}
+	c.pongHandler = func(string) error { return nil }
+	return c
+}
+
+// Context returns the zen Context of the upgraded request.
+func (c *Conn) Context() *zen.Context {
+	return c.ctx
+}
+
+// Subprotocol returns the negotiated subprotocol, or an empty string if none was negotiated.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed reports whether permessage-deflate was negotiated.
+func (c *Conn) Compressed() bool {
+	return c.compress
+}
+
+// RemoteAddr returns the network address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// NetConn returns the underlying network connection.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// SetReadLimit sets the maximum size in bytes of a message read from the peer,
+// after decompression. Larger messages close the connection with
+// CloseMessageTooBig. A limit of zero or less disables the check, except that
+// a single frame may not exceed 64MB.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetReadDeadline sets the deadline for future reads.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future writes.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// EnableWriteCompression enables or disables compression of outgoing messages.
+// It has no effect if permessage-deflate was not negotiated.
+func (c *Conn) EnableWriteCompression(enable bool) {
+	c.writeCompression = enable
+}
+
+// SetPingHandler sets the handler for ping messages. The default handler
+// replies with a pong carrying the same application data.
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	c.pingHandler = h
+}
+
+// SetPongHandler sets the handler for pong messages. The default does nothing.
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	c.pongHandler = h
+}
+
+// ReadMessage reads the next data message, reassembling fragmented messages
+// and handling interleaved control frames. Text messages are validated as UTF-8.
+// When the peer closes the connection a *CloseError is returned.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+
+	started := false
+	compressed := false
+	var message []byte
+
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+
+		switch f.opcode {
+		case PingMessage, PongMessage, CloseMessage:
+			if err := c.handleControl(f); err != nil {
+				return 0, nil, c.fail(err)
+			}
+			continue
+		case continuationFrame:
+			if !started {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "continuation frame without a message"})
+			}
+			if f.rsv1 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "RSV1 set on continuation frame"})
+			}
+		case TextMessage, BinaryMessage:
+			if started {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "new message before previous message finished"})
+			}
+			if f.rsv1 && !c.compress {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "RSV1 set without negotiated compression"})
+			}
+			started = true
+			compressed = f.rsv1
+			messageType = f.opcode
+		default:
+			return 0, nil, c.fail(&protocolError{CloseProtocolError, fmt.Sprintf("unknown opcode %d", f.opcode)})
+		}
+
+		if c.readLimit > 0 && int64(len(message)+len(f.payload)) > c.readLimit {
+			return 0, nil, c.fail(ErrReadLimit)
+		}
+		message = append(message, f.payload...)
+
+		if !f.fin {
+			continue
+		}
+
+		if compressed {
+			if message, err = decompressMessage(message, c.readLimit); err != nil {
+				return 0, nil, c.fail(err)
+			}
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, c.fail(&protocolError{CloseInvalidFramePayloadData, "invalid UTF-8 in text message"})
+		}
+		return messageType, message, nil
+	}
+}
+
+// ReadJSON reads the next message and decodes it as JSON into v.
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteMessage writes a data or control message.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case PingMessage, PongMessage, CloseMessage:
+		return c.WriteControl(messageType, data, time.Time{})
+	default:
+		return ErrInvalidType
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+
+	rsv1 := false
+	if c.compress && c.writeCompression {
+		compressed, err := compressMessage(data, c.compressionLevel)
+		if err != nil {
+			return err
+		}
+		data = compressed
+		rsv1 = true
+	}
+	return c.writeFrame(messageType, rsv1, data)
+}
+
+// WriteJSON encodes v as JSON and writes it as a text message.
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// WriteControl writes a ping, pong or close control message with an optional deadline.
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != PingMessage && messageType != PongMessage && messageType != CloseMessage {
+		return ErrInvalidType
+	}
+	if len(data) > maxControlPayload {
+		return ErrControlTooBig
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+	if !deadline.IsZero() {
+		c.conn.SetWriteDeadline(deadline)
+		defer c.conn.SetWriteDeadline(time.Time{})
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	return c.writeFrame(messageType, false, data)
+}
+
+// Ping sends a ping message with the given application data.
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteControl(PingMessage, data, time.Time{})
+}
+
+// Close sends a close message with the given code and reason, then closes the
+// underlying connection.
+func (c *Conn) Close(code int, reason string) error {
+	err := c.WriteControl(CloseMessage, FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	if errors.Is(err, ErrClosed) {
+		err = nil
+	}
+	if closeErr := c.conn.Close(); err == nil && closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+		err = closeErr
+	}
+	return err
+}
+
+// FormatCloseMessage formats a close code and reason as a close message payload.
+// The reason is truncated to fit in a control frame.
+func FormatCloseMessage(code int, reason string) []byte {
+	if code == CloseNoStatusReceived {
+		// 1005 must not be sent on the wire, an empty payload means the same
+		return []byte{}
+	}
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+	buf := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], reason)
+	return buf
+}
+
+// handleControl processes a control frame received while reading
+func (c *Conn) handleControl(f frame) error {
+	switch f.opcode {
+	case PingMessage:
+		return c.pingHandler(string(f.payload))
+	case PongMessage:
+		return c.pongHandler(string(f.payload))
+	}
+
+	code := CloseNoStatusReceived
+	text := ""
+	switch {
+	case len(f.payload) == 1:
+		return &protocolError{CloseProtocolError, "invalid close payload"}
+	case len(f.payload) >= 2:
+		code = int(binary.BigEndian.Uint16(f.payload))
+		text = string(f.payload[2:])
+		if !validCloseCode(code) {
+			return &protocolError{CloseProtocolError, fmt.Sprintf("invalid close code %d", code)}
+		}
+		if !utf8.ValidString(text) {
+			return &protocolError{CloseInvalidFramePayloadData, "invalid UTF-8 in close reason"}
+		}
+	}
+
+	// Echo the close code back and close the connection
+	reply := code
+	if reply == CloseNoStatusReceived {
+		reply = CloseNormalClosure
+	}
+	c.WriteControl(CloseMessage, FormatCloseMessage(reply, ""), time.Now().Add(time.Second))
+	c.conn.Close()
+	return &CloseError{Code: code, Text: text}
+}
+
+// fail records a read error, closing the connection with a matching close
+// code when the peer violated the protocol
+func (c *Conn) fail(err error) error {
+	var closeErr *CloseError
+	var protoErr *protocolError
+
+	switch {
+	case errors.As(err, &closeErr):
+	case errors.As(err, &protoErr):
+		c.Close(protoErr.code, protoErr.msg)
+	case errors.Is(err, ErrReadLimit):
+		c.Close(CloseMessageTooBig, "message too big")
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		c.conn.Close()
+		err = &CloseError{Code: CloseAbnormalClosure, Text: err.Error()}
+	default:
+		c.conn.Close()
+	}
+
+	c.readErr = err
+	return err
+}
+
+// validCloseCode reports whether a close code may be received on the wire
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// frame is a single decoded WebSocket frame
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+// readFrame reads and unmasks a single frame
+func (c *Conn) readFrame() (frame, error) {
+	var f frame
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return f, err
+	}
+
+	f.fin = header[0]&0x80 != 0
+	f.rsv1 = header[0]&0x40 != 0
+	f.opcode = int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	if header[0]&0x30 != 0 {
+		return f, &protocolError{CloseProtocolError, "RSV2 or RSV3 set"}
+	}
+	if masked != c.isServer {
+		// Clients must mask every frame and servers must not
+		return f, &protocolError{CloseProtocolError, "incorrect frame masking"}
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+		if length < 0 {
+			return f, &protocolError{CloseProtocolError, "invalid frame length"}
+		}
+	}
+
+	if f.opcode >= CloseMessage {
+		if !f.fin {
+			return f, &protocolError{CloseProtocolError, "fragmented control frame"}
+		}
+		if length > maxControlPayload {
+			return f, &protocolError{CloseProtocolError, "control frame too big"}
+		}
+	} else if limit := c.readLimit; length > limit && (limit > 0 || length > maxFramePayload) {
+		return f, ErrReadLimit
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, maskKey[:]); err != nil {
+			return f, err
+		}
+	}
+
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return f, err
+	}
+	if masked {
+		maskBytes(maskKey, f.payload)
+	}
+	return f, nil
+}
+
+// writeFrame writes a single unfragmented frame. The caller must hold writeMu.
+func (c *Conn) writeFrame(opcode int, rsv1 bool, payload []byte) error {
+	header := make([]byte, 0, 14)
+
+	b0 := byte(0x80 | opcode)
+	if rsv1 {
+		b0 |= 0x40
+	}
+	header = append(header, b0)
+
+	var maskBit byte
+	if !c.isServer {
+		maskBit = 0x80
+	}
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		header = append(header, maskBit|byte(length))
+	case length <= 0xffff:
+		header = append(header, maskBit|126, byte(length>>8), byte(length))
+	default:
+		header = append(header, maskBit|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if !c.isServer {
+		var maskKey [4]byte
+		if _, err := rand.Read(maskKey[:]); err != nil {
+			return err
+		}
+		header = append(header, maskKey[:]...)
+		masked := make([]byte, length)
+		copy(masked, payload)
+		maskBytes(maskKey, masked)
+		payload = masked
+	}
+
+	buffers := net.Buffers{header, payload}
+	_, err := buffers.WriteTo(c.conn)
+	return err
+}
+
+// maskBytes applies the masking key to data in place
+func maskBytes(key [4]byte, data []byte) {
+	for i := range data {
+		data[i] ^= key[i%4]
+	}
+}
@@ -0,0 +1,309 @@
+package websocket
+
+// This file implements a rooms/broadcast hub. Every registered client gets a
+// bounded send queue drained by its own goroutine, so a slow client never blocks
+// a broadcast; when its queue is full the client is evicted and closed.
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// custom errors
+var (
+	ErrSlowConsumer = errors.New("websocket: client send queue is full")
+	ErrClientClosed = errors.New("websocket: client is closed")
+)
+
+// HubConfig holds the configuration for a Hub
+type HubConfig struct {
+	// SendBuffer is the number of messages queued per client before it is
+	// evicted as a slow consumer. Default 256.
+	SendBuffer int
+
+	// WriteTimeout limits how long writing a single message may take. Default 10 seconds.
+	WriteTimeout time.Duration
+
+	// OnEvict is called when a client is evicted for being too slow.
+	OnEvict func(*Client)
+}
+
+// DefaultHubConfig returns the default Hub configuration
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		SendBuffer:   256,
+		WriteTimeout: 10 * time.Second,
+	}
+}
+
+// Hub groups connections into rooms and broadcasts messages to them.
+type Hub struct {
+	config  HubConfig
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+	rooms   map[string]map[*Client]struct{}
+	closed  bool
+}
+
+// Client is a connection registered with a Hub.
+type Client struct {
+	Conn *Conn
+
+	hub   *Hub
+	send  chan outgoing
+	rooms map[string]struct{} // guarded by hub.mu
+	done  chan struct{}
+	once  sync.Once
+	evict sync.Once // evicts the client once, however many sends find its queue full
+}
+
+// outgoing is a message queued for a client
+type outgoing struct {
+	messageType int
+	data        []byte
+}
+
+// NewHub creates a new Hub
+//
+// Usage:
+//
+//	hub := websocket.NewHub()
+//	app.GET("/chat/:room", websocket.Handler(func(conn *websocket.Conn) {
+//	    client := hub.Register(conn)
+//	    defer client.Close()
+//	    client.Join(conn.Context().GetParam("room"))
+//
+//	    for {
+//	        messageType, data, err := conn.ReadMessage()
+//	        if err != nil {
+//	            return
+//	        }
+//	        hub.Broadcast(conn.Context().GetParam("room"), messageType, data)
+//	    }
+//	}))
+func NewHub(config ...HubConfig) *Hub {
+	cfg := DefaultHubConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.SendBuffer <= 0 {
+		cfg.SendBuffer = DefaultHubConfig().SendBuffer
+	}
+
+	return &Hub{
+		config:  cfg,
+		clients: make(map[*Client]struct{}),
+		rooms:   make(map[string]map[*Client]struct{}),
+	}
+}
+
+// Register adds a connection to the hub and starts delivering its queued
+// messages. After Close, the connection is closed with CloseGoingAway and the
+// returned client is already done.
+func (h *Hub) Register(conn *Conn) *Client {
+	client := &Client{
+		Conn:  conn,
+		hub:   h,
+		send:  make(chan outgoing, h.config.SendBuffer),
+		rooms: make(map[string]struct{}),
+		done:  make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		client.close(CloseGoingAway, "server shutting down")
+		return client
+	}
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+
+	go client.writePump()
+	return client
+}
+
+// Broadcast queues a message for every client in the room and returns the
+// number of clients it was queued for.
+func (h *Hub) Broadcast(room string, messageType int, data []byte) int {
+	h.mu.RLock()
+	targets := make([]*Client, 0, len(h.rooms[room]))
+	for client := range h.rooms[room] {
+		targets = append(targets, client)
+	}
+	h.mu.RUnlock()
+
+	return h.deliver(targets, messageType, data)
+}
+
+// BroadcastAll queues a message for every registered client.
+func (h *Hub) BroadcastAll(messageType int, data []byte) int {
+	h.mu.RLock()
+	targets := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		targets = append(targets, client)
+	}
+	h.mu.RUnlock()
+
+	return h.deliver(targets, messageType, data)
+}
+
+// RoomSize returns the number of clients in a room.
+func (h *Hub) RoomSize(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Clients returns the number of registered clients.
+func (h *Hub) Clients() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// Close closes every registered client with CloseGoingAway. Connections
+// registered afterwards are closed right away.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		client.close(CloseGoingAway, "server shutting down")
+	}
+}
+
+// deliver queues a message for each target, evicting clients whose queue is full
+func (h *Hub) deliver(targets []*Client, messageType int, data []byte) int {
+	delivered := 0
+	for _, client := range targets {
+		if err := client.Send(messageType, data); err == nil {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// Join adds the client to a room.
+func (cl *Client) Join(room string) {
+	h := cl.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, registered := h.clients[cl]; !registered {
+		return
+	}
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*Client]struct{})
+	}
+	h.rooms[room][cl] = struct{}{}
+	cl.rooms[room] = struct{}{}
+}
+
+// Leave removes the client from a room.
+func (cl *Client) Leave(room string) {
+	h := cl.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(cl, room)
+}
+
+// Rooms returns the rooms the client has joined.
+func (cl *Client) Rooms() []string {
+	cl.hub.mu.RLock()
+	defer cl.hub.mu.RUnlock()
+
+	rooms := make([]string, 0, len(cl.rooms))
+	for room := range cl.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// Send queues a message for the client. If the queue is full the client is
+// evicted and closed with CloseTryAgainLater.
+func (cl *Client) Send(messageType int, data []byte) error {
+	select {
+	case <-cl.done:
+		return ErrClientClosed
+	default:
+	}
+
+	select {
+	case cl.send <- outgoing{messageType: messageType, data: data}:
+		return nil
+	default:
+		cl.evict.Do(func() {
+			if cl.hub.config.OnEvict != nil {
+				cl.hub.config.OnEvict(cl)
+			}
+			go cl.close(CloseTryAgainLater, "slow consumer")
+		})
+		return ErrSlowConsumer
+	}
+}
+
+// Done returns a channel that is closed when the client is closed or evicted.
+func (cl *Client) Done() <-chan struct{} {
+	return cl.done
+}
+
+// Close removes the client from the hub and closes its connection.
+func (cl *Client) Close() {
+	cl.close(CloseNormalClosure, "")
+}
+
+// close unregisters the client and closes the connection with the given code
+func (cl *Client) close(code int, reason string) {
+	cl.once.Do(func() {
+		h := cl.hub
+		h.mu.Lock()
+		for room := range cl.rooms {
+			h.leave(cl, room)
+		}
+		delete(h.clients, cl)
+		h.mu.Unlock()
+
+		close(cl.done)
+		if code == CloseAbnormalClosure {
+			// 1006 is never sent on the wire, the connection is simply dropped
+			cl.Conn.NetConn().Close()
+			return
+		}
+		cl.Conn.Close(code, reason)
+	})
+}
+
+// leave removes a client from a room. The caller must hold the lock.
+func (h *Hub) leave(cl *Client, room string) {
+	if clients := h.rooms[room]; clients != nil {
+		delete(clients, cl)
+		if len(clients) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+	delete(cl.rooms, room)
+}
+
+// writePump writes queued messages to the connection until the client is closed
+func (cl *Client) writePump() {
+	for {
+		select {
+		case <-cl.done:
+			return
+		case msg := <-cl.send:
+			if cl.hub.config.WriteTimeout > 0 {
+				cl.Conn.SetWriteDeadline(time.Now().Add(cl.hub.config.WriteTimeout))
+			}
+			if err := cl.Conn.WriteMessage(msg.messageType, msg.data); err != nil {
+				cl.close(CloseAbnormalClosure, "")
+				return
+			}
+		}
+	}
+}
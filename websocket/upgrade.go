@@ -0,0 +1,236 @@
+package websocket
+
+import (
+	"bufio"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ThembinkosiThemba/zen"
+)
+
+// acceptGUID is the fixed GUID used to compute Sec-WebSocket-Accept (RFC 6455 section 1.3)
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// custom errors
+var (
+	ErrBadHandshake     = errors.New("websocket: invalid upgrade request")
+	ErrBadVersion       = errors.New("websocket: unsupported version")
+	ErrOriginNotAllowed = errors.New("websocket: origin not allowed")
+	ErrHijackFailed     = errors.New("websocket: response does not support hijacking")
+)
+
+// Config defines the config for WebSocket upgrades
+type Config struct {
+	// Subprotocols lists the supported subprotocols in order of preference.
+	// The first one also offered by the client is selected.
+	Subprotocols []string
+
+	// CheckOrigin decides whether a request's Origin is allowed.
+	// Default allows requests without an Origin and same-host origins.
+	CheckOrigin func(*zen.Context) bool
+
+	// EnableCompression negotiates permessage-deflate when the client offers it.
+	// Default is false.
+	EnableCompression bool
+
+	// CompressionLevel is the flate level used for outgoing messages.
+	// Default is flate.BestSpeed.
+	CompressionLevel int
+
+	// ReadLimit is the maximum message size in bytes. Default is 1MB.
+	ReadLimit int64
+
+	// HandshakeTimeout limits how long writing the handshake response may take.
+	// Default is 10 seconds.
+	HandshakeTimeout time.Duration
+
+	// OnError handles failed upgrades. Default responds with the status as text.
+	OnError func(c *zen.Context, status int, err error)
+}
+
+// DefaultConfig returns the default WebSocket configuration
+func DefaultConfig() Config {
+	return Config{
+		CheckOrigin:      sameOrigin,
+		CompressionLevel: flate.BestSpeed,
+		ReadLimit:        1 << 20,
+		HandshakeTimeout: 10 * time.Second,
+		OnError: func(c *zen.Context, status int, err error) {
+			c.Text(status, "%s", http.StatusText(status))
+		},
+	}
+}
+
+// Handler returns a zen.HandlerFunc that upgrades the request to a WebSocket
+// connection and runs handler with it. The connection is closed when handler returns.
+//
+// Usage:
+//
+//	app.GET("/ws", websocket.Handler(func(conn *websocket.Conn) {
+//	    for {
+//	        messageType, data, err := conn.ReadMessage()
+//	        if err != nil {
+//	            return
+//	        }
+//	        conn.WriteMessage(messageType, data)
+//	    }
+//	}))
+func Handler(handler func(*Conn), config ...Config) zen.HandlerFunc {
+	return func(c *zen.Context) {
+		conn, err := Upgrade(c, config...)
+		if err != nil {
+			zen.Debugf("websocket upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close(CloseNormalClosure, "")
+
+		handler(conn)
+	}
+}
+
+// Upgrade performs the WebSocket handshake and takes over the connection.
+// On failure an error response has already been written.
+func Upgrade(c *zen.Context, config ...Config) (*Conn, error) {
+	cfg := DefaultConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.CheckOrigin == nil {
+		cfg.CheckOrigin = DefaultConfig().CheckOrigin
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = DefaultConfig().OnError
+	}
+	if cfg.CompressionLevel < flate.HuffmanOnly || cfg.CompressionLevel > flate.BestCompression {
+		cfg.CompressionLevel = DefaultConfig().CompressionLevel
+	}
+
+	fail := func(status int, err error) (*Conn, error) {
+		cfg.OnError(c, status, err)
+		return nil, err
+	}
+
+	r := c.Request
+	if r.Method != http.MethodGet ||
+		!headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, ErrBadHandshake)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return fail(http.StatusUpgradeRequired, ErrBadVersion)
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, ErrBadHandshake)
+	}
+	if !cfg.CheckOrigin(c) {
+		return fail(http.StatusForbidden, ErrOriginNotAllowed)
+	}
+
+	subprotocol := selectSubprotocol(r.Header, cfg.Subprotocols)
+	extensions, compress := "", false
+	if cfg.EnableCompression {
+		extensions, compress = negotiateDeflate(strings.Join(r.Header.Values("Sec-WebSocket-Extensions"), ","))
+	}
+
+	netConn, brw, err := c.Writer.Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, ErrHijackFailed)
+	}
+	// The server's read and write timeouts no longer apply to the hijacked connection
+	netConn.SetDeadline(time.Time{})
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	b.WriteString(acceptKey(key))
+	b.WriteString("\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		b.WriteString("Sec-WebSocket-Extensions: " + extensions + "\r\n")
+	}
+	b.WriteString("\r\n")
+
+	if cfg.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(cfg.HandshakeTimeout))
+	}
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	netConn.SetWriteDeadline(time.Time{})
+	c.Writer.StatusCode = http.StatusSwitchingProtocols
+
+	var br *bufio.Reader
+	if brw.Reader.Buffered() > 0 {
+		br = brw.Reader
+	}
+	conn := newConn(netConn, br, true)
+	conn.ctx = c
+	conn.subprotocol = subprotocol
+	conn.compress = compress
+	conn.writeCompression = compress
+	conn.compressionLevel = cfg.CompressionLevel
+	conn.readLimit = cfg.ReadLimit
+	return conn, nil
+}
+
+// IsWebSocketUpgrade reports whether the request asks for a WebSocket upgrade.
+func IsWebSocketUpgrade(c *zen.Context) bool {
+	return headerContainsToken(c.Request.Header, "Connection", "upgrade") &&
+		headerContainsToken(c.Request.Header, "Upgrade", "websocket")
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value for a client key
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// sameOrigin allows requests without an Origin header and requests whose Origin host matches Host
+func sameOrigin(c *zen.Context) bool {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, c.Request.Host)
+}
+
+// selectSubprotocol returns the first supported subprotocol that the client offered
+func selectSubprotocol(header http.Header, supported []string) string {
+	offered := strings.Split(strings.Join(header.Values("Sec-WebSocket-Protocol"), ","), ",")
+	for _, protocol := range supported {
+		for _, offer := range offered {
+			if strings.TrimSpace(offer) == protocol {
+				return protocol
+			}
+		}
+	}
+	return ""
+}
+
+// headerContainsToken reports whether a comma separated header contains token, ignoring case
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,327 @@
+package websocket
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ThembinkosiThemba/zen"
+)
+
+// dial performs a client handshake against the test server and returns a client Conn
+func dial(t *testing.T, server *httptest.Server, path string, headers map[string]string) (*Conn, *http.Response) {
+	t.Helper()
+
+	netConn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { netConn.Close() })
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	req := "GET " + path + " HTTP/1.1\r\nHost: " + netConn.RemoteAddr().String() +
+		"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: " + key +
+		"\r\nSec-WebSocket-Version: 13\r\n"
+	for k, v := range headers {
+		req += k + ": " + v + "\r\n"
+	}
+	if _, err := netConn.Write([]byte(req + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, resp
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected Sec-WebSocket-Accept %q", got)
+	}
+
+	conn := newConn(netConn, br, false)
+	conn.compress = strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), permessageDeflate)
+	conn.writeCompression = conn.compress
+	conn.compressionLevel = 1
+	return conn, resp
+}
+
+func echoServer(t *testing.T, config ...Config) *httptest.Server {
+	app := zen.New()
+	app.GET("/ws", Handler(func(conn *Conn) {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}, config...))
+
+	server := httptest.NewServer(app)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEcho(t *testing.T) {
+	server := echoServer(t)
+	conn, _ := dial(t, server, "/ws", nil)
+
+	for _, msg := range []struct {
+		messageType int
+		data        string
+	}{
+		{TextMessage, "hello"},
+		{BinaryMessage, strings.Repeat("x", 70000)},
+	} {
+		if err := conn.WriteMessage(msg.messageType, []byte(msg.data)); err != nil {
+			t.Fatal(err)
+		}
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if messageType != msg.messageType || string(data) != msg.data {
+			t.Errorf("Expected echo of type %d with %d bytes, got type %d with %d bytes", msg.messageType, len(msg.data), messageType, len(data))
+		}
+	}
+
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(appData string) error {
+		pong <- appData
+		return nil
+	})
+	if err := conn.Ping([]byte("are you there")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("after ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "after ping" {
+		t.Fatalf("Expected echo after ping, got %q %v", data, err)
+	}
+	if got := <-pong; got != "are you there" {
+		t.Errorf("Expected pong payload to match ping, got %q", got)
+	}
+
+	conn.WriteControl(CloseMessage, FormatCloseMessage(CloseGoingAway, "bye"), time.Now().Add(time.Second))
+	_, _, err := conn.ReadMessage()
+	if !IsCloseError(err, CloseGoingAway) {
+		t.Errorf("Expected close %d to be echoed, got %v", CloseGoingAway, err)
+	}
+}
+
+func TestFragmentedMessage(t *testing.T) {
+	server := echoServer(t)
+	conn, _ := dial(t, server, "/ws", nil)
+
+	conn.writeMu.Lock()
+	frames := []struct {
+		header byte
+		data   string
+	}{
+		{TextMessage, "frag"},               // first frame, FIN not set
+		{0x80 | PingMessage, "interleaved"}, // control frames may be interleaved
+		{0x80 | continuationFrame, "mented"},
+	}
+	for _, f := range frames {
+		if err := writeRawFrame(conn, f.header, []byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.writeMu.Unlock()
+
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "fragmented" {
+		t.Errorf("Expected reassembled message %q, got %q", "fragmented", data)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	server := echoServer(t, Config{EnableCompression: true})
+	conn, resp := dial(t, server, "/ws", map[string]string{
+		"Sec-WebSocket-Extensions": "permessage-deflate; client_max_window_bits",
+	})
+
+	want := "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+	if got := resp.Header.Get("Sec-WebSocket-Extensions"); got != want {
+		t.Fatalf("Expected extension response %q, got %q", want, got)
+	}
+
+	message := strings.Repeat("compress me ", 1000)
+	if err := conn.WriteMessage(TextMessage, []byte(message)); err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != message {
+		t.Error("Compressed message was not echoed intact")
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	server := echoServer(t, Config{ReadLimit: 16})
+	conn, _ := dial(t, server, "/ws", nil)
+
+	if err := conn.WriteMessage(TextMessage, []byte(strings.Repeat("a", 17))); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := conn.ReadMessage()
+	if !IsCloseError(err, CloseMessageTooBig) {
+		t.Errorf("Expected close %d, got %v", CloseMessageTooBig, err)
+	}
+}
+
+func TestUnmaskedClientFrame(t *testing.T) {
+	server := echoServer(t)
+	conn, _ := dial(t, server, "/ws", nil)
+
+	// Pretend to be a server so the frame is sent unmasked
+	conn.isServer = true
+	conn.WriteMessage(TextMessage, []byte("unmasked"))
+	conn.isServer = false
+
+	_, _, err := conn.ReadMessage()
+	if !IsCloseError(err, CloseProtocolError) {
+		t.Errorf("Expected close %d, got %v", CloseProtocolError, err)
+	}
+}
+
+func TestInvalidHandshake(t *testing.T) {
+	server := echoServer(t)
+
+	resp, err := http.Get(server.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	_, resp = dial(t, server, "/ws", map[string]string{"Origin": "http://evil.example"})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status code %d for foreign origin, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestHub_Broadcast(t *testing.T) {
+	hub := NewHub()
+	app := zen.New()
+	app.GET("/rooms/:room", Handler(func(conn *Conn) {
+		client := hub.Register(conn)
+		defer client.Close()
+		client.Join(conn.Context().GetParam("room"))
+
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			hub.Broadcast(conn.Context().GetParam("room"), messageType, data)
+		}
+	}))
+	server := httptest.NewServer(app)
+	defer server.Close()
+
+	alice, _ := dial(t, server, "/rooms/lobby", nil)
+	bob, _ := dial(t, server, "/rooms/lobby", nil)
+	carol, _ := dial(t, server, "/rooms/other", nil)
+
+	deadline := time.Now().Add(time.Second)
+	for (hub.RoomSize("lobby") < 2 || hub.RoomSize("other") < 1) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := alice.WriteMessage(TextMessage, []byte("hi lobby")); err != nil {
+		t.Fatal(err)
+	}
+	for _, conn := range []*Conn{alice, bob} {
+		if _, data, err := conn.ReadMessage(); err != nil || string(data) != "hi lobby" {
+			t.Errorf("Expected broadcast %q, got %q %v", "hi lobby", data, err)
+		}
+	}
+
+	carol.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, _, err := carol.ReadMessage(); err == nil {
+		t.Error("Clients in other rooms should not receive the broadcast")
+	}
+
+	hub.Close()
+	if _, _, err := bob.ReadMessage(); !IsCloseError(err, CloseGoingAway) {
+		t.Errorf("Expected close %d after hub close, got %v", CloseGoingAway, err)
+	}
+
+	// Connections registered after Close are closed right away
+	dave, _ := dial(t, server, "/rooms/lobby", nil)
+	if _, _, err := dave.ReadMessage(); !IsCloseError(err, CloseGoingAway) {
+		t.Errorf("Expected close %d when registering with a closed hub, got %v", CloseGoingAway, err)
+	}
+	if hub.Clients() != 0 {
+		t.Errorf("Expected no clients in a closed hub, got %d", hub.Clients())
+	}
+}
+
+func TestHub_EvictOnce(t *testing.T) {
+	var evictions atomic.Int32
+	hub := NewHub(HubConfig{SendBuffer: 1, OnEvict: func(*Client) { evictions.Add(1) }})
+
+	// Nothing reads the other end of the pipe, so the client's writes block
+	server, peer := net.Pipe()
+	defer peer.Close()
+	client := hub.Register(newConn(server, bufio.NewReader(server), true))
+
+	for i := 0; i < 10; i++ {
+		client.Send(TextMessage, []byte("update"))
+	}
+	if n := evictions.Load(); n != 1 {
+		t.Errorf("Expected the slow client to be evicted once, got %d", n)
+	}
+}
+
+func TestOversizedFrame(t *testing.T) {
+	for _, limit := range []int64{0, 16} {
+		server, peer := net.Pipe()
+		defer peer.Close()
+		conn := newConn(server, bufio.NewReader(server), true)
+		conn.SetReadLimit(limit)
+
+		// A header declaring a huge payload must not be allocated
+		go func() {
+			header := []byte{0x82, 0x80 | 127, 0x40, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+			peer.Write(header)
+			io.Copy(io.Discard, peer)
+		}()
+		if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrReadLimit) {
+			t.Errorf("Expected %v with limit %d, got %v", ErrReadLimit, limit, err)
+		}
+	}
+}
+
+// writeRawFrame writes a masked client frame with the given first header byte.
+// The caller must hold writeMu.
+func writeRawFrame(conn *Conn, b0 byte, payload []byte) error {
+	key := [4]byte{1, 2, 3, 4}
+	masked := append([]byte(nil), payload...)
+	maskBytes(key, masked)
+
+	frame := append([]byte{b0, 0x80 | byte(len(payload))}, key[:]...)
+	_, err := conn.conn.Write(append(frame, masked...))
+	return err
+}
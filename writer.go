@@ -1,20 +1,29 @@
 package zen
 
-import "net/http"
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
 
 // ResponseWriter wraps the http.ResponseWriter to capture and manage the HTTP status code
 // along with ensuring the status code is written only once during the request-response cycle.
+// It exposes the optional interfaces of the underlying writer (http.Flusher, http.Hijacker,
+// http.Pusher and io.ReaderFrom) so streaming and WebSocket upgrades work through it.
 type ResponseWriter struct {
 	http.ResponseWriter      // The embedded ResponseWriter used for writing the response.
 	StatusCode          int  // Captures the HTTP status code set for the response.
 	headerWritten       bool // A flag to indicate if the header has already been written.
+	hijacked            bool // A flag to indicate the connection was taken over with Hijack.
+	size                int64
 }
 
 // WriteHeader captures the status code and calls the underlying ResponseWriter's WriteHeader method.
 // It ensures that the status code is written only once and prevents overwriting.
 func (w *ResponseWriter) WriteHeader(statusCode int) {
-	// Prevents writing the header more than once.
-	if w.headerWritten {
+	// Prevents writing the header more than once, or at all once the connection is hijacked.
+	if w.headerWritten || w.hijacked {
 		return
 	}
 	w.StatusCode = statusCode                // Set the status code for the response.
@@ -22,6 +31,16 @@ func (w *ResponseWriter) WriteHeader(statusCode int) {
 	w.headerWritten = true                   // Marks the header as written to prevent further writes.
 }
 
+// Write writes the response body, sending a 200 status first if none was written.
+func (w *ResponseWriter) Write(data []byte) (int, error) {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.size += int64(n)
+	return n, err
+}
+
 // Status returns the current HTTP status code. If no status code is set, it returns http.StatusOK.
 func (w *ResponseWriter) Status() int {
 	// If no status code has been set, return HTTP Status OK (200).
@@ -31,6 +50,98 @@ func (w *ResponseWriter) Status() int {
 	return w.StatusCode
 }
 
+// Size returns the number of body bytes written so far.
+func (w *ResponseWriter) Size() int64 {
+	return w.size
+}
+
+// Written reports whether the status code has been sent.
+func (w *ResponseWriter) Written() bool {
+	return w.headerWritten
+}
+
+// Flush sends any buffered data to the client. It implements http.Flusher and is a
+// no-op if the underlying ResponseWriter cannot flush.
+func (w *ResponseWriter) Flush() {
+	if w.hijacked {
+		return
+	}
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, as needed for WebSocket upgrades.
+// It implements http.Hijacker and returns http.ErrNotSupported if the underlying
+// ResponseWriter cannot be hijacked.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Hijacked reports whether the connection was taken over with Hijack.
+func (w *ResponseWriter) Hijacked() bool {
+	return w.hijacked
+}
+
+// Push initiates an HTTP/2 server push. It implements http.Pusher and returns
+// http.ErrNotSupported if the underlying ResponseWriter does not support push.
+func (w *ResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// DeclareTrailers announces trailer keys in the Trailer header. It must be called
+// before the header is written; clients such as gRPC expect trailers to be announced.
+func (w *ResponseWriter) DeclareTrailers(keys ...string) {
+	for _, key := range keys {
+		w.Header().Add("Trailer", key)
+	}
+}
+
+// SetTrailer sets a trailer sent after the body. It can be called at any point,
+// also after the body has been written, and works over HTTP/1.1 chunked
+// responses and HTTP/2.
+func (w *ResponseWriter) SetTrailer(key, value string) {
+	w.Header().Set(http.TrailerPrefix+key, value)
+}
+
+// ReadFrom copies from r to the response, letting the underlying writer use
+// sendfile where possible. It implements io.ReaderFrom.
+func (w *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	var n int64
+	var err error
+	if readerFrom, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = readerFrom.ReadFrom(r)
+	} else {
+		n, err = io.Copy(w.ResponseWriter, r)
+	}
+	w.size += n
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter, for use with http.ResponseController.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // NewResponseWriter creates and returns a new ResponseWriter instance.
 // It wraps the provided http.ResponseWriter and initializes the StatusCode to 0 and headerWritten flag to false.
 func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
@@ -1,20 +1,47 @@
 package zen
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Engine is the core framework instance for managing routing and middleware for the Zen framework.
 type Engine struct {
-	*RouterGroup                // - RouterGroup: Provides group-based routing and middleware chaining.
-	router       *Router        // - router: The main router instance that handles route registration and dispatching.
-	groups       []*RouterGroup // - groups: A collection of all RouterGroups associated with the engine.
-	addr         string         // - addr: The address where the server is bound (host:port).
-	ctx          Context        // - ctx: A default context used for server operations like shutdown.
+	*RouterGroup                      // - RouterGroup: Provides group-based routing and middleware chaining.
+	router          *Router           // - router: The main router instance that handles route registration and dispatching.
+	groups          []*RouterGroup    // - groups: A collection of all RouterGroups associated with the engine.
+	addr            string            // - addr: The address where the server is bound (host:port).
+	ctx             Context           // - ctx: A default context used for server operations like shutdown.
+	envelope        EnvelopeFunc      // - envelope: Shapes the body written by Success and Error responses.
+	namedRoutes     map[string]Route  // - namedRoutes: Routes registered with a name, used for URL generation.
+	templates       *templateRegistry // - templates: The HTML templates loaded with LoadTemplates.
+	redirectHosts   []string          // - redirectHosts: Extra hosts Context.Redirect may send clients to.
+	bodyLimit       int64             // - bodyLimit: Default maximum request body size in bytes, 0 for none.
+	uploadConfig    *UploadConfig     // - uploadConfig: Default limits for multipart uploads.
+	trustedProxies  []*net.IPNet      // - trustedProxies: Proxies whose forwarding headers are trusted.
+	platformHeaders []string          // - platformHeaders: Opted in platform headers holding the client IP.
+	devCertDir      string            // - devCertDir: Where ServeTLSDev caches its CA and certificate.
+	secretKey       []byte            // - secretKey: Signs cookies such as flash messages, see SetSecretKey.
+	mode            engineMode        // - mode: The mode of the engine, DevMode, Production or Test.
+	logger          *Log              // - logger: The logger of the engine, which follows its mode.
+
+	serverMu     sync.Mutex    // - serverMu: Guards server and serverDone.
+	server       *http.Server  // - server: The server currently serving the engine, nil when stopped.
+	serverDone   chan struct{} // - serverDone: Closed when the current server has been shut down.
+	serverConfig ServerConfig  // - serverConfig: The configuration applied to every server the engine starts.
+	onStart      []Hook        // - onStart: Hooks run before the server starts.
+	onShutdown   []Hook        // - onShutdown: Hooks run after the server has drained.
+
+	sinksMu sync.Mutex // - sinksMu: Guards sinks.
+	sinks   []logSink  // - sinks: Log writers of the engine's middleware, flushed and closed on shutdown.
 }
 
 type Engine2 struct {
@@ -39,93 +66,133 @@ type Route struct {
 	Path   string // - Path: The URL path pattern (e.g., "/users/:id") for the route.
 }
 
+// RouteRef is returned when a route is registered and allows it to be named.
+type RouteRef struct {
+	Route
+	engine *Engine
+}
+
+// Name gives the route a name so URLs can be generated for it with Engine.URL,
+// the "url" template function and Context.RedirectToRoute.
+//
+// Usage:
+//
+//	app.GET("/users/:id", showUser).Name("users.show")
+//	app.URL("users.show", "id", "42") // "/users/42"
+func (r *RouteRef) Name(name string) *RouteRef {
+	r.engine.namedRoutes[name] = r.Route
+	return r
+}
+
 // New creates a new Engine instance.
 // Initializes routing capabilities and returns a new Engine instance.
 func New() *Engine {
 	engine := &Engine{
-		router: NewRouter(),
+		router:       NewRouter(),
+		namedRoutes:  make(map[string]Route),
+		serverConfig: DefaultServerConfig(),
 	}
+	engine.logger = NewLogger()
+	engine.logger.mode = &engine.mode
 
 	engine.RouterGroup = &RouterGroup{engine: engine}
 	engine.groups = []*RouterGroup{engine.RouterGroup}
 	return engine
 }
 
-// Serve starts an HTTP server on the given address.
+// Serve starts an HTTP server on the given address, configured with SetServerConfig.
 // - addr: The address (host:port) where the server will listen.
-// - Returns an error if the server fails to start or if address resolution fails.
+// - Returns an error if the address can't be bound or the server fails.
 func (e *Engine) Serve(addr string) error {
-	newAddr, err := resolveAddress(addr)
+	listener, err := e.listen(addr)
 	if err != nil {
 		return err
 	}
+	return e.ServeListener(listener)
+}
 
-	if IsDevMode() {
-		e.printRoutes()
-		fmt.Print(e.zenAsciiArt(newAddr))
+// ServeListener serves HTTP on an already open listener, configured with SetServerConfig.
+// The listener is closed when the server stops.
+// - listener: The listener to accept connections on, e.g. one opened before dropping privileges.
+// - Returns an error if the server fails.
+func (e *Engine) ServeListener(listener net.Listener) error {
+	server, done := e.newServer(listener.Addr().String())
+	if err := e.start(context.Background(), server.Addr); err != nil {
+		e.abortStart(listener, done)
+		return err
 	}
 
-	return http.ListenAndServe(newAddr, e)
+	return server.Serve(listener)
 }
 
-// ServeTLS starts an HTTPS server on the given address using TLS.
+// ServeTLS starts an HTTPS server on the given address using TLS, configured with SetServerConfig.
 // - addr: The address (host:port) where the server will listen.
-// - certFile: Path to the TLS certificate file.
-// - keyFile: Path to the TLS key file.
-// - Returns an error if the server fails to start or if address resolution fails.
+// - certFile: Path to the TLS certificate file. May be empty if ServerConfig.TLSConfig holds certificates.
+// - keyFile: Path to the TLS key file. May be empty if ServerConfig.TLSConfig holds certificates.
+// - Returns an error if the address can't be bound or the server fails.
 func (e *Engine) ServeTLS(addr, certFile, keyFile string) error {
-	newAddr, err := resolveAddress(addr)
+	return e.serveTLS(addr, nil, certFile, keyFile)
+}
+
+// serveTLS serves HTTPS on addr with config, or ServerConfig.TLSConfig if config is nil
+func (e *Engine) serveTLS(addr string, config *tls.Config, certFile, keyFile string) error {
+	listener, err := e.listen(addr)
 	if err != nil {
 		return err
 	}
 
-	if IsDevMode() {
-		e.printRoutes()
-		fmt.Print(e.zenAsciiArt(newAddr))
+	server, done := e.newServer(listener.Addr().String())
+	if config != nil {
+		server.TLSConfig = config
+	}
+	if err := e.start(context.Background(), server.Addr); err != nil {
+		e.abortStart(listener, done)
+		return err
 	}
 
-	return http.ListenAndServeTLS(newAddr, certFile, keyFile, e)
+	return server.ServeTLS(listener, certFile, keyFile)
 }
 
 // ServeWithTimeout starts an HTTP server with timeout settings on the given address.
 // - addr: The address (host:port) where the server will listen.
 // - timeout: Duration for read, write, and idle timeouts.
 // - Returns an error if the server fails to start or if address resolution fails.
+//
+// Deprecated: Use SetServerConfig, which sets each timeout separately, and Serve.
 func (e *Engine) ServeWithTimeout(addr string, timeout time.Duration) error {
-	newAddr, err := resolveAddress(addr)
-	if err != nil {
-		return err
-	}
-
-	srv := &http.Server{
-		Addr:         newAddr,
-		Handler:      e,
-		ReadTimeout:  timeout,
-		WriteTimeout: timeout,
-		IdleTimeout:  timeout * 2,
-	}
-	if IsDevMode() {
-		e.printRoutes()
-		fmt.Print(e.zenAsciiArt(newAddr))
-	}
-
-	return srv.ListenAndServe()
+	e.serverConfig.ReadTimeout = timeout
+	e.serverConfig.WriteTimeout = timeout
+	e.serverConfig.IdleTimeout = timeout * 2
+	return e.Serve(addr)
 }
 
-// Shutdown gracefully shuts down the server.
-// - timeout: The maximum duration to wait for existing connections to close.
-// - Returns an error if shutdown fails.
+// Shutdown gracefully shuts down the server and runs the OnShutdown hooks.
+// - timeout: The maximum duration to wait for in-flight requests to finish. Connections
+// still open after it are closed.
+// - Returns an error if shutdown fails or a hook fails.
 func (engine *Engine) Shutdown(timeout time.Duration) error {
-	server := &http.Server{
-		Addr:    engine.addr,
-		Handler: engine,
+	engine.serverMu.Lock()
+	server, done := engine.server, engine.serverDone
+	engine.server, engine.serverDone = nil, nil
+	engine.serverMu.Unlock()
+
+	if server == nil {
+		return nil
 	}
+	defer close(done)
 
-	var ctx, cancel = engine.ctx.DefaultContext()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	return server.Shutdown(ctx)
+	err := server.Shutdown(ctx)
+	if err != nil {
+		engine.logger.Warnf("server did not drain within %v: %v", timeout, err)
+		server.Close()
+	}
 
+	hookCtx, hookCancel := context.WithTimeout(context.Background(), timeout)
+	defer hookCancel()
+	return errors.Join(err, engine.runShutdownHooks(hookCtx))
 }
 
 // ServeHTTP implements the http.Handler interface for the Engine.
@@ -134,6 +201,12 @@ func (engine *Engine) Shutdown(timeout time.Duration) error {
 // - Delegates request handling to the router.
 func (e *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c := NewContext(w, req)
+	c.engine = e
+	defer c.cleanup()
+	c.bodyLimit = e.bodyLimit
+	if req.Body != nil {
+		req.Body = &limitedBody{c: c, rc: req.Body}
+	}
 	e.router.handle(c)
 }
 
@@ -161,58 +234,35 @@ func (engine *Engine) Routes() []Route {
 	return routes
 }
 
-// checkPort validates and resolves a port for the given address.
-// - addr: The address (host:port) to check.
-// - Returns: A valid, available port and an error if resolution fails.
-// - If the port is in use, it increments until a free one is found.
-func checkPort(addr string) (int, error) {
-	_, portStr, err := net.SplitHostPort(addr)
-	if err != nil {
-		return 0, fmt.Errorf("invalid port number: %v", err)
-	}
-
-	port := 0
-	_, err = fmt.Sscanf(portStr, "%d", &port)
-	if err != nil {
-		return 0, fmt.Errorf("invalid port number: %v", err)
+// URL builds the path of a named route, substituting the ":name" segments of its
+// pattern with the given key/value pairs.
+// - name: The name given to the route with RouteRef.Name.
+// - params: Alternating parameter names and values, e.g. "id", "42".
+// - Returns an error if the route is unknown or a parameter is missing.
+func (engine *Engine) URL(name string, params ...string) (string, error) {
+	route, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("no route named %q", name)
 	}
-
-	for {
-		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-		if err != nil {
-			if strings.Contains(err.Error(), "address already in use") {
-				port++
-				continue
-			}
-			return 0, err
-		}
-		listener.Close()
-		return port, nil
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("route %q: parameters must be key/value pairs", name)
 	}
-}
 
-// resolveAddress resolves the final address for the server to bind to.
-// - addr: The input address (host:port).
-// - Returns: A resolved address string and an error if resolution fails.
-// - Logs a warning if the port is already in use and changes the port.
-func resolveAddress(addr string) (string, error) {
-	port, err := checkPort(addr)
-	if err != nil {
-		Errorf("port check failed: %v", err)
-		return "", fmt.Errorf("port check failed: %v", err)
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
 	}
 
-	newAddr := fmt.Sprintf(":%d", port)
-	if strings.Contains(addr, ":") {
-		host, _, _ := net.SplitHostPort(addr)
-		if host != "" {
-			newAddr = fmt.Sprintf("%s:%d", host, port)
+	segments := strings.Split(route.Path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
 		}
+		value, ok := values[segment[1:]]
+		if !ok {
+			return "", fmt.Errorf("route %q: missing parameter %q", name, segment[1:])
+		}
+		segments[i] = url.PathEscape(value)
 	}
-
-	if newAddr != addr {
-		Infof("Port %s in in use. Using port %d instead", addr, port)
-	}
-
-	return newAddr, nil
+	return strings.Join(segments, "/"), nil
 }
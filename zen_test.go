@@ -233,3 +233,24 @@ func TestEngine_NestedGroups(t *testing.T) {
 		t.Error("Handler was not called")
 	}
 }
+
+func TestEngine_URL(t *testing.T) {
+	engine := New()
+	api := engine.GroupRoutes("/api")
+	api.GET("/users/:id/posts/:postId", func(c *Context) {}).Name("posts.show")
+
+	url, err := engine.URL("posts.show", "id", "42", "postId", "a b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "/api/users/42/posts/a%20b" {
+		t.Errorf("Expected URL %q, got %q", "/api/users/42/posts/a%20b", url)
+	}
+
+	if _, err := engine.URL("posts.show", "id", "42"); err == nil {
+		t.Error("Expected an error for a missing parameter")
+	}
+	if _, err := engine.URL("unknown"); err == nil {
+		t.Error("Expected an error for an unknown route")
+	}
+}